@@ -0,0 +1,70 @@
+// Command admin runs maintenance tasks against the rate limiter's storage.
+// It reads the same environment as the server.
+//
+//	admin purge [-namespace name]
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/tiago-kimura/rate-limiter/internal/backend"
+	"github.com/tiago-kimura/rate-limiter/internal/config"
+	"github.com/tiago-kimura/rate-limiter/pkg/storage"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	switch os.Args[1] {
+	case "purge":
+		purge(cfg, os.Args[2:])
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: admin purge [-namespace name]")
+	os.Exit(2)
+}
+
+// purge deletes every key of a namespace: counters, blocks and leases.
+func purge(cfg *config.Config, args []string) {
+	flags := flag.NewFlagSet("purge", flag.ExitOnError)
+	namespace := flags.String("namespace", cfg.StorageNamespace, "namespace to purge (defaults to STORAGE_NAMESPACE)")
+	timeout := flags.Duration("timeout", time.Minute, "how long the purge may take")
+	flags.Parse(args)
+
+	backendStorage, err := backend.Open(cfg)
+	if err != nil {
+		log.Fatalf("Failed to open %s storage: %v", cfg.StorageBackend, err)
+	}
+	defer backendStorage.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	store, err := storage.NewNamespacedStorage(backendStorage, *namespace)
+	if err != nil {
+		log.Fatalf("Failed to purge namespace %q: %v", *namespace, err)
+	}
+
+	deleted, err := store.Purge(ctx)
+	if err != nil {
+		log.Fatalf("Failed to purge namespace %q after deleting %d keys: %v", *namespace, deleted, err)
+	}
+
+	log.Printf("Purged namespace %q: %d keys deleted", *namespace, deleted)
+}
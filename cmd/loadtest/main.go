@@ -0,0 +1,262 @@
+// Command loadtest sends concurrent traffic from many IPs and tokens to a
+// rate limited endpoint and reports throughput, latency percentiles, the
+// status breakdown and how closely the admitted requests match the
+// configured limits.
+//
+// The expected admits come from a reference RateLimiter on in-memory
+// storage, checked with the same identities just before each request is
+// sent. Pass the limits the server runs with, e.g.
+//
+//	loadtest -url http://localhost:8080/api/test -duration 30s -ips 50 \
+//		-tokens abc123,vip_token -ip-limit 10 -token-limit 100
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tiago-kimura/rate-limiter/pkg/middleware"
+	"github.com/tiago-kimura/rate-limiter/pkg/ratelimiter"
+	"github.com/tiago-kimura/rate-limiter/pkg/storage"
+)
+
+type options struct {
+	url         string
+	duration    time.Duration
+	concurrency int
+	rate        float64
+	timeout     time.Duration
+
+	ips         int
+	tokens      []string
+	tokenRatio  float64
+	tokenHeader string
+
+	ipConfig    ratelimiter.Config
+	tokenConfig ratelimiter.Config
+}
+
+func parseOptions() options {
+	var o options
+	var tokens string
+
+	flag.StringVar(&o.url, "url", "http://localhost:8080/api/test", "endpoint to load")
+	flag.DurationVar(&o.duration, "duration", 10*time.Second, "how long to send traffic")
+	flag.IntVar(&o.concurrency, "concurrency", 50, "number of concurrent workers")
+	flag.Float64Var(&o.rate, "rate", 0, "target requests per second across all workers (0: as fast as possible)")
+	flag.DurationVar(&o.timeout, "timeout", 5*time.Second, "per-request timeout")
+
+	flag.IntVar(&o.ips, "ips", 10, "number of client IPs, sent as X-Forwarded-For")
+	flag.StringVar(&tokens, "tokens", "", "comma-separated tokens")
+	flag.Float64Var(&o.tokenRatio, "token-ratio", 0.5, "fraction of requests carrying a token")
+	flag.StringVar(&o.tokenHeader, "token-header", middleware.DefaultAPIKeyHeader, "header carrying the token")
+
+	flag.Int64Var(&o.ipConfig.Limit, "ip-limit", 10, "server's IP_RATE_LIMIT")
+	flag.DurationVar(&o.ipConfig.Window, "ip-window", time.Second, "server's IP_RATE_WINDOW")
+	flag.DurationVar(&o.ipConfig.BlockTime, "ip-block", 5*time.Minute, "server's IP_BLOCK_TIME")
+	flag.Int64Var(&o.tokenConfig.Limit, "token-limit", 100, "server's limit for the tokens")
+	flag.DurationVar(&o.tokenConfig.Window, "token-window", time.Second, "server's window for the tokens")
+	flag.DurationVar(&o.tokenConfig.BlockTime, "token-block", 5*time.Minute, "server's block time for the tokens")
+
+	flag.Parse()
+
+	for _, token := range strings.Split(tokens, ",") {
+		if token = strings.TrimSpace(token); token != "" {
+			o.tokens = append(o.tokens, token)
+		}
+	}
+	if o.ips < 1 || o.concurrency < 1 {
+		log.Fatal("-ips and -concurrency must be at least 1")
+	}
+
+	return o
+}
+
+// stats are collected per worker and merged at the end.
+type stats struct {
+	latencies []time.Duration
+	statuses  map[int]int
+	errors    int
+	expected  int
+}
+
+func (s *stats) merge(other *stats) {
+	s.latencies = append(s.latencies, other.latencies...)
+	for status, count := range other.statuses {
+		s.statuses[status] += count
+	}
+	s.errors += other.errors
+	s.expected += other.expected
+}
+
+func newStats() *stats {
+	return &stats{statuses: make(map[int]int)}
+}
+
+type loadTest struct {
+	options
+	client    *http.Client
+	reference *ratelimiter.RateLimiter
+}
+
+func newLoadTest(o options) *loadTest {
+	var opts []ratelimiter.Option
+	for _, token := range o.tokens {
+		opts = append(opts, ratelimiter.WithTokenConfig(token, o.tokenConfig))
+	}
+
+	return &loadTest{
+		options: o,
+		client: &http.Client{
+			Timeout:   o.timeout,
+			Transport: &http.Transport{MaxIdleConnsPerHost: o.concurrency},
+		},
+		reference: ratelimiter.NewRateLimiter(storage.NewMockStorage(), o.ipConfig, opts...),
+	}
+}
+
+func (lt *loadTest) run(ctx context.Context) (*stats, time.Duration) {
+	var ticks <-chan time.Time
+	if lt.rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / lt.rate))
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+
+	results := make([]*stats, lt.concurrency)
+	var wg sync.WaitGroup
+	start := time.Now()
+
+	for i := range results {
+		results[i] = newStats()
+		wg.Add(1)
+		go func(s *stats, rng *rand.Rand) {
+			defer wg.Done()
+			for {
+				if ticks != nil {
+					select {
+					case <-ticks:
+					case <-ctx.Done():
+						return
+					}
+				}
+				if ctx.Err() != nil {
+					return
+				}
+				lt.send(ctx, s, rng)
+			}
+		}(results[i], rand.New(rand.NewSource(int64(i))))
+	}
+
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	total := newStats()
+	for _, s := range results {
+		total.merge(s)
+	}
+	return total, elapsed
+}
+
+func (lt *loadTest) send(ctx context.Context, s *stats, rng *rand.Rand) {
+	n := rng.Intn(lt.ips)
+	ip := fmt.Sprintf("10.%d.%d.%d", n>>16&0xff, n>>8&0xff, n&0xff)
+	var token string
+	if len(lt.tokens) > 0 && rng.Float64() < lt.tokenRatio {
+		token = lt.tokens[rng.Intn(len(lt.tokens))]
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, lt.url, nil)
+	if err != nil {
+		log.Fatalf("Invalid -url: %v", err)
+	}
+	req.Header.Set("X-Forwarded-For", ip)
+	if token != "" {
+		req.Header.Set(lt.tokenHeader, token)
+	}
+
+	expected, err := lt.reference.CheckLimit(ctx, ip, token)
+	if err != nil {
+		return
+	}
+
+	start := time.Now()
+	resp, err := lt.client.Do(req)
+	if err != nil {
+		// Requests cut off by the end of the test are not counted.
+		if ctx.Err() == nil {
+			s.errors++
+		}
+		return
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	s.latencies = append(s.latencies, time.Since(start))
+	s.statuses[resp.StatusCode]++
+	if expected.Allowed {
+		s.expected++
+	}
+}
+
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	return sorted[int(p*float64(len(sorted)-1))]
+}
+
+func report(s *stats, elapsed time.Duration) {
+	sort.Slice(s.latencies, func(i, j int) bool { return s.latencies[i] < s.latencies[j] })
+	requests := len(s.latencies)
+
+	fmt.Printf("Requests:    %d in %v (%.1f req/s)\n", requests, elapsed.Round(time.Millisecond), float64(requests)/elapsed.Seconds())
+	fmt.Printf("Errors:      %d\n", s.errors)
+
+	fmt.Printf("Latency:     p50 %v  p90 %v  p99 %v  max %v\n",
+		percentile(s.latencies, 0.5), percentile(s.latencies, 0.9), percentile(s.latencies, 0.99), percentile(s.latencies, 1))
+
+	statuses := make([]int, 0, len(s.statuses))
+	for status := range s.statuses {
+		statuses = append(statuses, status)
+	}
+	sort.Ints(statuses)
+	fmt.Println("Statuses:")
+	for _, status := range statuses {
+		fmt.Printf("  %d  %d (%.1f%%)\n", status, s.statuses[status], 100*float64(s.statuses[status])/float64(requests))
+	}
+
+	admitted := s.statuses[http.StatusOK]
+	fmt.Printf("Admitted:    %d, expected %d", admitted, s.expected)
+	if s.expected > 0 {
+		fmt.Printf(" (%.1f%%)", 100*float64(admitted)/float64(s.expected))
+	}
+	fmt.Println()
+}
+
+func main() {
+	o := parseOptions()
+
+	ctx, cancel := context.WithTimeout(context.Background(), o.duration)
+	defer cancel()
+
+	fmt.Printf("Loading %s for %v with %d workers, %d IPs and %d tokens\n", o.url, o.duration, o.concurrency, o.ips, len(o.tokens))
+
+	s, elapsed := newLoadTest(o).run(ctx)
+	if len(s.latencies) == 0 {
+		fmt.Fprintln(os.Stderr, "No responses received")
+		os.Exit(1)
+	}
+
+	report(s, elapsed)
+}
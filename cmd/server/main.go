@@ -1,85 +1,338 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/tiago-kimura/rate-limiter/internal/backend"
 	"github.com/tiago-kimura/rate-limiter/internal/config"
-	"github.com/tiago-kimura/rate-limiter/internal/middleware"
-	"github.com/tiago-kimura/rate-limiter/internal/ratelimiter"
-	"github.com/tiago-kimura/rate-limiter/internal/storage"
+	"github.com/tiago-kimura/rate-limiter/internal/health"
+	"github.com/tiago-kimura/rate-limiter/internal/logging"
+	"github.com/tiago-kimura/rate-limiter/internal/proxy"
+	"github.com/tiago-kimura/rate-limiter/pkg/checkapi"
+	"github.com/tiago-kimura/rate-limiter/pkg/metrics"
+	"github.com/tiago-kimura/rate-limiter/pkg/middleware"
+	"github.com/tiago-kimura/rate-limiter/pkg/ratelimiter"
+	"github.com/tiago-kimura/rate-limiter/pkg/rls"
+	"github.com/tiago-kimura/rate-limiter/pkg/storage"
+	"google.golang.org/grpc"
 )
 
 type Response struct {
 	Message   string    `json:"message"`
 	Timestamp time.Time `json:"timestamp"`
 	IP        string    `json:"ip,omitempty"`
-	Token     string    `json:"token,omitempty"`
+	HasToken  bool      `json:"has_token,omitempty"`
 }
 
 func main() {
 	cfg, err := config.Load()
 	if err != nil {
-		log.Fatalf("Failed to load configuration: %v", err)
+		fatal("failed to load configuration", err)
 	}
 
-	redisStorage, err := storage.NewRedisStorage(cfg.RedisURL)
+	logger, err := logging.New(os.Stderr, cfg.LogLevel, cfg.LogFormat)
 	if err != nil {
-		log.Fatalf("Failed to connect to Redis: %v", err)
+		fatal("failed to create logger", err)
 	}
-	defer redisStorage.Close()
+	slog.SetDefault(logger)
 
-	rateLimiter := ratelimiter.NewRateLimiter(redisStorage, cfg.GetIPConfig())
+	backendStorage, err := backend.Open(cfg)
+	if err != nil {
+		fatal("failed to open storage", err, "backend", cfg.StorageBackend)
+	}
+	defer backendStorage.Close()
 
-	for token, tokenConfig := range cfg.TokenConfigs {
-		rateLimiter.SetTokenConfig(token, ratelimiter.Config{
-			Limit:     tokenConfig.Limit,
-			Window:    tokenConfig.Window,
-			BlockTime: tokenConfig.BlockTime,
-		})
+	store, err := storage.NewNamespacedStorage(backendStorage, cfg.StorageNamespace)
+	if err != nil {
+		fatal("failed to open storage", err, "namespace", cfg.StorageNamespace)
+	}
+
+	var opts []ratelimiter.Option
+	for token := range cfg.TokenConfigs {
+		tokenConfig, _ := cfg.GetTokenConfig(token)
+		if cfg.TokenConfigHashed {
+			opts = append(opts, ratelimiter.WithHashedTokenConfig(token, tokenConfig))
+		} else {
+			opts = append(opts, ratelimiter.WithTokenConfig(token, tokenConfig))
+		}
+	}
+
+	if cfg.TokenHashSecret != "" {
+		opts = append(opts, ratelimiter.WithTokenHashSecret([]byte(cfg.TokenHashSecret)))
+	}
+
+	for tier, tierConfig := range cfg.GetTierConfigs() {
+		opts = append(opts, ratelimiter.WithTierConfig(tier, tierConfig))
 	}
 
-	rateLimiterMiddleware := middleware.NewRateLimiterMiddleware(rateLimiter)
+	tokenTiers := ratelimiter.StaticTiers{}
+	if cfg.TokenTiersFile != "" {
+		tokenTiers, err = ratelimiter.LoadTokenTiersFile(cfg.TokenTiersFile)
+		if err != nil {
+			fatal("failed to load token tiers", err)
+		}
+	}
+	for token, tier := range cfg.TokenTiers {
+		tokenTiers[token] = tier
+	}
+	if cfg.TokenHashSecret != "" && !cfg.TokenConfigHashed {
+		hashedTiers := make(ratelimiter.StaticTiers, len(tokenTiers))
+		for token, tier := range tokenTiers {
+			hashedTiers[ratelimiter.HashToken([]byte(cfg.TokenHashSecret), token)] = tier
+		}
+		tokenTiers = hashedTiers
+	}
+	opts = append(opts, ratelimiter.WithTierResolver(tokenTiers))
+
+	if cfg.LocalCacheBatchSize > 0 {
+		opts = append(opts, ratelimiter.WithLocalCache(ratelimiter.LocalCacheConfig{
+			BatchSize:    cfg.LocalCacheBatchSize,
+			SyncInterval: cfg.LocalCacheSyncInterval,
+		}))
+	}
+
+	if cfg.AuditLogEnabled {
+		opts = append(opts, ratelimiter.WithAuditLogger(logger.With("log", "audit")))
+	}
+
+	if cfg.AdaptiveEnabled {
+		opts = append(opts, ratelimiter.WithAdaptiveLimiter(ratelimiter.NewAdaptiveLimiter(cfg.GetAdaptiveConfig())))
+	}
 
-	router := mux.NewRouter()
+	rateLimiter := ratelimiter.NewRateLimiter(store, cfg.GetIPConfig(), opts...)
+
+	ipExtractor := middleware.IP()
+	if cfg.Mode == config.ModeProxy || len(cfg.TrustedProxies) > 0 {
+		// Clients reach the proxy directly, so forwarding headers are only
+		// believed when they come from a trusted proxy in front of it.
+		ipExtractor = middleware.TrustedIP(cfg.TrustedProxies...)
+	}
+
+	keyExtractor := middleware.FirstOf(middleware.TokenHeader(middleware.DefaultAPIKeyHeader), ipExtractor)
+	if cfg.JWTJWKSFile != "" {
+		keys, err := middleware.LoadJWKSFile(cfg.JWTJWKSFile)
+		if err != nil {
+			fatal("failed to load JWKS", err)
+		}
+
+		keyExtractor = middleware.FirstOf(
+			middleware.JWT(middleware.JWTConfig{
+				Keys:      keys,
+				Header:    cfg.JWTHeader,
+				KeyClaim:  cfg.JWTKeyClaim,
+				TierClaim: cfg.JWTTierClaim,
+				Issuer:    cfg.JWTIssuer,
+				Audience:  cfg.JWTAudience,
+			}),
+			keyExtractor,
+		)
+	}
+
+	middlewareOpts := []middleware.Option{middleware.WithKeyExtractor(keyExtractor)}
+	if len(cfg.CountedStatuses) > 0 {
+		middlewareOpts = append(middlewareOpts, middleware.WithCountedStatuses(cfg.CountedStatuses...))
+	}
+	if cfg.BandwidthLimit > 0 || cfg.BandwidthRate > 0 {
+		bandwidthLimiter := ratelimiter.NewBandwidthLimiter(store, rateLimiter, cfg.GetBandwidthConfig())
+		middlewareOpts = append(middlewareOpts, middleware.WithBandwidthLimiter(bandwidthLimiter))
+	}
+	if cfg.ShedCapacity > 0 {
+		middlewareOpts = append(middlewareOpts, middleware.WithShedder(ratelimiter.NewShedder(store, cfg.GetSheddingConfig())))
+	}
+
+	rateLimiterMiddleware := middleware.NewRateLimiterMiddleware(rateLimiter, middlewareOpts...)
+
+	registry := prometheus.NewRegistry()
+
+	storageMetrics := metrics.NewStorage(registry)
+	storageHealth := storage.NewHealthMonitor(store, cfg.StorageHealthInterval,
+		storage.WithHealthObserver(func(err error, latency time.Duration) {
+			storageMetrics.ObservePing(err, latency)
+		}),
+	)
+
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+	go storageHealth.Run(backgroundCtx)
+	go rateLimiter.RunLocalCache(backgroundCtx)
+
+	checker := health.NewChecker(storageHealth, 2*time.Second)
+
+	root := mux.NewRouter()
+
+	// Health endpoints are not rate limited, so probes never get a 429.
+	root.HandleFunc("/livez", checker.Livez).Methods("GET")
+	root.HandleFunc("/readyz", checker.Readyz).Methods("GET")
+	root.HandleFunc("/health", checker.Readyz).Methods("GET")
+
+	if cfg.MetricsPath != "" {
+		root.Handle(cfg.MetricsPath, promhttp.HandlerFor(registry, promhttp.HandlerOpts{})).Methods("GET")
+	}
+
+	if cfg.DecisionPath != "" {
+		root.Handle(cfg.DecisionPath, rateLimiterMiddleware.DecisionHandler(cfg.DecisionDeniedStatus))
+	}
+
+	if cfg.CheckAPIEnabled {
+		checkHandler := checkapi.NewHandler(rateLimiter)
+		root.Handle(checkapi.CheckPath, checkHandler.CheckHandler()).Methods("POST")
+		root.Handle(checkapi.BatchCheckPath, checkHandler.BatchCheckHandler()).Methods("POST")
+	}
+
+	router := root.PathPrefix("/").Subrouter()
 
 	router.Use(rateLimiterMiddleware.Handler)
 
-	router.HandleFunc("/health", healthHandler).Methods("GET")
+	if cfg.ConcurrencyLimit > 0 {
+		concurrencyLimiter := ratelimiter.NewConcurrencyLimiter(store, rateLimiter, ratelimiter.ConcurrencyConfig{
+			Limit:    cfg.ConcurrencyLimit,
+			LeaseTTL: cfg.ConcurrencyLeaseTTL,
+		})
 
-	router.HandleFunc("/", homeHandler).Methods("GET")
-	router.HandleFunc("/api/test", testHandler).Methods("GET", "POST")
-	router.HandleFunc("/api/data", dataHandler).Methods("GET")
+		concurrencyMiddleware := middleware.NewConcurrencyMiddleware(concurrencyLimiter,
+			middleware.WithConcurrencyKeyExtractor(keyExtractor),
+			middleware.WithConcurrencyMetrics(metrics.NewConcurrency(registry)),
+		)
+		router.Use(concurrencyMiddleware.Handler)
+	}
 
 	server := &http.Server{
 		Addr:         ":" + cfg.Port,
-		Handler:      router,
+		Handler:      root,
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
 
-	log.Printf("Rate Limiter Server starting on port %s", cfg.Port)
-	log.Printf("Redis URL: %s", cfg.RedisURL)
-	log.Printf("IP Rate Limit: %d requests per %v", cfg.IPRateLimit, cfg.IPRateWindow)
-	log.Printf("Token Rate Limit: %d requests per %v", cfg.TokenRateLimit, cfg.TokenRateWindow)
+	switch cfg.Mode {
+	case config.ModeProxy:
+		routes, err := proxy.ParseRoutes(cfg.ProxyRoutes)
+		if err != nil {
+			fatal("invalid proxy routes", err)
+		}
+
+		reverseProxy, err := proxy.New(routes, cfg.ProxyUpstreamTimeout, proxy.WithTrustedProxies(cfg.TrustedProxies...))
+		if err != nil {
+			fatal("failed to create proxy", err)
+		}
+
+		router.PathPrefix("/").Handler(reverseProxy)
 
-	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		log.Fatalf("Server failed to start: %v", err)
+		// Streamed responses are bounded by the upstream timeout instead.
+		server.WriteTimeout = 0
+
+		for _, route := range routes {
+			slog.Info("proxying", "prefix", route.Prefix, "upstreams", route.Upstreams)
+		}
+	default:
+		router.HandleFunc("/", homeHandler).Methods("GET")
+		router.HandleFunc("/api/test", testHandler).Methods("GET", "POST")
+		router.HandleFunc("/api/data", dataHandler).Methods("GET")
 	}
-}
 
-func healthHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	response := Response{
-		Message:   "Rate limiter service is healthy",
-		Timestamp: time.Now(),
+	var grpcServer *grpc.Server
+	if cfg.RLSPort != "" {
+		rules, err := rls.LoadRulesFile(cfg.RLSRulesFile, rateLimiter)
+		if err != nil {
+			fatal("failed to load rate limit service rules", err)
+		}
+
+		listener, err := net.Listen("tcp", ":"+cfg.RLSPort)
+		if err != nil {
+			fatal("failed to listen on RLS port", err)
+		}
+
+		grpcServer = grpc.NewServer()
+		rls.NewService(rateLimiter, rules).Register(grpcServer)
+
+		go func() {
+			slog.Info("envoy rate limit service listening", "port", cfg.RLSPort)
+			if err := grpcServer.Serve(listener); err != nil {
+				fatal("rate limit service failed", err)
+			}
+		}()
 	}
-	json.NewEncoder(w).Encode(response)
+
+	slog.Info("rate limiter server starting", "port", cfg.Port, "mode", cfg.Mode)
+	if cfg.StorageBackend == config.StorageBolt {
+		slog.Info("storage", "backend", cfg.StorageBackend, "path", cfg.BoltPath, "namespace", cfg.StorageNamespace)
+	} else {
+		slog.Info("storage", "backend", cfg.StorageBackend, "url", cfg.RedisURL, "namespace", cfg.StorageNamespace)
+	}
+	slog.Info("ip rate limit", "limit", cfg.IPRateLimit, "window", cfg.IPRateWindow.String())
+	slog.Info("token rate limit", "limit", cfg.TokenRateLimit, "window", cfg.TokenRateWindow.String())
+	if cfg.AdaptiveEnabled {
+		slog.Info("adaptive limits", "latency_target", cfg.AdaptiveLatencyTarget.String(), "error_rate", cfg.AdaptiveErrorRate)
+	}
+	if cfg.BandwidthLimit > 0 || cfg.BandwidthRate > 0 {
+		slog.Info("bandwidth limit", "bytes", cfg.BandwidthLimit, "window", cfg.BandwidthWindow.String(), "bytes_per_second", cfg.BandwidthRate)
+	}
+	if cfg.ShedCapacity > 0 {
+		slog.Info("load shedding", "capacity", cfg.ShedCapacity, "window", cfg.ShedWindow.String(), "thresholds", cfg.ShedThresholds)
+	}
+	if cfg.ConcurrencyLimit > 0 {
+		slog.Info("concurrency limit", "in_flight", cfg.ConcurrencyLimit)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		if err != nil && err != http.ErrServerClosed {
+			fatal("server failed to start", err)
+		}
+	case <-ctx.Done():
+		stop()
+		slog.Info("shutting down, draining requests", "drain_delay", cfg.ShutdownDrainDelay.String(), "timeout", cfg.ShutdownTimeout.String())
+
+		// Keep serving while readiness fails, so load balancers stop
+		// routing here before the listener closes.
+		checker.Drain(context.Background(), cfg.ShutdownDrainDelay)
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+		defer cancel()
+
+		if grpcServer != nil {
+			go func() {
+				<-shutdownCtx.Done()
+				grpcServer.Stop()
+			}()
+			grpcServer.GracefulStop()
+		}
+
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			slog.Warn("graceful shutdown did not complete", "error", err)
+		}
+
+		if err := rateLimiter.FlushLocalCache(shutdownCtx); err != nil {
+			slog.Error("failed to flush local counters", "error", err)
+		}
+	}
+
+	slog.Info("server stopped")
+}
+
+func fatal(msg string, err error, args ...any) {
+	slog.Error(msg, append([]any{"error", err}, args...)...)
+	os.Exit(1)
 }
 
 func homeHandler(w http.ResponseWriter, r *http.Request) {
@@ -92,7 +345,7 @@ func homeHandler(w http.ResponseWriter, r *http.Request) {
 		Message:   "Welcome to the Rate Limiter API",
 		Timestamp: time.Now(),
 		IP:        ip,
-		Token:     token,
+		HasToken:  token != "",
 	}
 	json.NewEncoder(w).Encode(response)
 }
@@ -107,7 +360,7 @@ func testHandler(w http.ResponseWriter, r *http.Request) {
 		Message:   fmt.Sprintf("Test endpoint accessed via %s", r.Method),
 		Timestamp: time.Now(),
 		IP:        ip,
-		Token:     token,
+		HasToken:  token != "",
 	}
 	json.NewEncoder(w).Encode(response)
 }
@@ -122,7 +375,7 @@ func dataHandler(w http.ResponseWriter, r *http.Request) {
 		"message":   "Data retrieved successfully",
 		"timestamp": time.Now(),
 		"ip":        ip,
-		"token":     token,
+		"has_token": token != "",
 		"data": []map[string]interface{}{
 			{"id": 1, "name": "Item 1", "value": 100},
 			{"id": 2, "name": "Item 2", "value": 200},
@@ -0,0 +1,32 @@
+// Package backend opens the storage backend selected by the configuration.
+package backend
+
+import (
+	"fmt"
+
+	"github.com/tiago-kimura/rate-limiter/internal/config"
+	"github.com/tiago-kimura/rate-limiter/pkg/storage"
+)
+
+// Backend is implemented by every storage the server can run on.
+type Backend interface {
+	storage.Storage
+	storage.LeaseStorage
+	storage.Purger
+}
+
+// Open connects to the backend named by STORAGE_BACKEND.
+func Open(cfg *config.Config) (Backend, error) {
+	switch cfg.StorageBackend {
+	case config.StorageBolt:
+		return storage.NewBoltStorage(cfg.BoltPath, storage.WithCompactionInterval(cfg.BoltCompactionInterval))
+	case config.StorageRedis:
+		opts := []storage.RedisOption{storage.WithConnectTimeout(cfg.RedisConnectTimeout)}
+		if cfg.RedisBatchWindow > 0 {
+			opts = append(opts, storage.WithBatching(cfg.RedisBatchWindow, cfg.RedisBatchSize))
+		}
+		return storage.NewRedisStorage(cfg.RedisURL, opts...)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.StorageBackend)
+	}
+}
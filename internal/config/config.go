@@ -2,53 +2,266 @@ package config
 
 import (
 	"fmt"
+	"log/slog"
+	"net/netip"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
-	"github.com/tiago-kimura/rate-limiter/internal/ratelimiter"
+	"github.com/tiago-kimura/rate-limiter/internal/logging"
+	"github.com/tiago-kimura/rate-limiter/pkg/ratelimiter"
+)
+
+const (
+	ModeDemo  = "demo"
+	ModeProxy = "proxy"
+
+	StorageRedis = "redis"
+	StorageBolt  = "bolt"
 )
 
 type Config struct {
 	Port string
+	Mode string
+
+	ShutdownTimeout    time.Duration
+	ShutdownDrainDelay time.Duration
+
+	LogLevel        slog.Level
+	LogFormat       string
+	AuditLogEnabled bool
+
+	ProxyRoutes          string
+	ProxyUpstreamTimeout time.Duration
+	TrustedProxies       []netip.Prefix
+
+	RLSPort      string
+	RLSRulesFile string
+
+	DecisionPath         string
+	DecisionDeniedStatus int
+
+	CheckAPIEnabled bool
+
+	ConcurrencyLimit    int64
+	ConcurrencyLeaseTTL time.Duration
+
+	AdaptiveEnabled       bool
+	AdaptiveLatencyTarget time.Duration
+	AdaptiveErrorRate     float64
+	AdaptiveInterval      time.Duration
+	AdaptiveMinSamples    int
+	AdaptiveMinFactor     float64
+
+	CountedStatuses []int
+
+	BandwidthLimit  int64
+	BandwidthWindow time.Duration
+	BandwidthRate   int64
+
+	ShedCapacity   int64
+	ShedWindow     time.Duration
+	ShedThresholds map[int]float64
+
+	MetricsPath string
+
+	StorageBackend         string
+	BoltPath               string
+	BoltCompactionInterval time.Duration
+
+	RedisURL            string
+	RedisConnectTimeout time.Duration
+	RedisBatchWindow    time.Duration
+	RedisBatchSize      int
 
-	RedisURL string
+	StorageNamespace      string
+	StorageHealthInterval time.Duration
+
+	LocalCacheBatchSize    int64
+	LocalCacheSyncInterval time.Duration
 
 	IPRateLimit     int64
 	IPRateWindow    time.Duration
 	IPBlockTime     time.Duration
+	IPPriority      int
 	TokenRateLimit  int64
 	TokenRateWindow time.Duration
 	TokenBlockTime  time.Duration
+	TokenPriority   int
 
 	TokenConfigs map[string]TokenConfig
+	TierConfigs  map[string]TokenConfig
+
+	TokenTiers     map[string]string
+	TokenTiersFile string
+
+	TokenHashSecret   string
+	TokenConfigHashed bool
+
+	JWTJWKSFile  string
+	JWTHeader    string
+	JWTKeyClaim  string
+	JWTTierClaim string
+	JWTIssuer    string
+	JWTAudience  string
 }
 
 type TokenConfig struct {
 	Limit     int64
 	Window    time.Duration
 	BlockTime time.Duration
+	Priority  int
 }
 
 func Load() (*Config, error) {
 	_ = godotenv.Load()
 
 	config := &Config{
-		Port:     getEnvString("PORT", "8080"),
-		RedisURL: getEnvString("REDIS_URL", "redis://localhost:6379/0"),
+		Port: getEnvString("PORT", "8080"),
+		Mode: getEnvString("MODE", ModeDemo),
+
+		ShutdownTimeout:    getEnvDuration("SHUTDOWN_TIMEOUT", "30s"),
+		ShutdownDrainDelay: getEnvDuration("SHUTDOWN_DRAIN_DELAY", "5s"),
+
+		LogFormat:       getEnvString("LOG_FORMAT", logging.FormatText),
+		AuditLogEnabled: getEnvBool("AUDIT_LOG_ENABLED", true),
+
+		ProxyRoutes:          getEnvString("PROXY_ROUTES", ""),
+		ProxyUpstreamTimeout: getEnvDuration("PROXY_UPSTREAM_TIMEOUT", "30s"),
+
+		RLSPort:      getEnvString("RLS_PORT", ""),
+		RLSRulesFile: getEnvString("RLS_RULES_FILE", ""),
+
+		DecisionPath:         getEnvString("DECISION_PATH", "/ratelimit/decision"),
+		DecisionDeniedStatus: int(getEnvInt64("DECISION_DENIED_STATUS", 429)),
+
+		CheckAPIEnabled: getEnvBool("CHECK_API_ENABLED", false),
+
+		ConcurrencyLimit:    getEnvInt64("CONCURRENCY_LIMIT", 0),
+		ConcurrencyLeaseTTL: getEnvDuration("CONCURRENCY_LEASE_TTL", "1m"),
+
+		AdaptiveEnabled:       getEnvBool("ADAPTIVE_ENABLED", false),
+		AdaptiveLatencyTarget: getEnvDuration("ADAPTIVE_LATENCY_TARGET", "500ms"),
+		AdaptiveErrorRate:     getEnvFloat("ADAPTIVE_ERROR_RATE", 0.1),
+		AdaptiveInterval:      getEnvDuration("ADAPTIVE_INTERVAL", "1s"),
+		AdaptiveMinSamples:    int(getEnvInt64("ADAPTIVE_MIN_SAMPLES", ratelimiter.DefaultAdaptiveMinSamples)),
+		AdaptiveMinFactor:     getEnvFloat("ADAPTIVE_MIN_FACTOR", ratelimiter.DefaultAdaptiveMinFactor),
+
+		BandwidthLimit:  getEnvInt64("BANDWIDTH_LIMIT", 0),
+		BandwidthWindow: getEnvDuration("BANDWIDTH_WINDOW", "1m"),
+		BandwidthRate:   getEnvInt64("BANDWIDTH_RATE", 0),
+
+		ShedCapacity: getEnvInt64("SHED_CAPACITY", 0),
+		ShedWindow:   getEnvDuration("SHED_WINDOW", "1s"),
+
+		MetricsPath: getEnvString("METRICS_PATH", "/metrics"),
+
+		StorageBackend:         getEnvString("STORAGE_BACKEND", StorageRedis),
+		BoltPath:               getEnvString("BOLT_PATH", "ratelimiter.db"),
+		BoltCompactionInterval: getEnvDuration("BOLT_COMPACTION_INTERVAL", "1m"),
+
+		RedisURL:            getEnvString("REDIS_URL", "redis://localhost:6379/0"),
+		RedisConnectTimeout: getEnvDuration("REDIS_CONNECT_TIMEOUT", "30s"),
+		RedisBatchWindow:    getEnvDuration("REDIS_BATCH_WINDOW", "0s"),
+		RedisBatchSize:      int(getEnvInt64("REDIS_BATCH_SIZE", 100)),
+
+		StorageNamespace:      getEnvString("STORAGE_NAMESPACE", ""),
+		StorageHealthInterval: getEnvDuration("STORAGE_HEALTH_INTERVAL", "5s"),
+
+		LocalCacheBatchSize:    getEnvInt64("LOCAL_CACHE_BATCH_SIZE", 0),
+		LocalCacheSyncInterval: getEnvDuration("LOCAL_CACHE_SYNC_INTERVAL", "100ms"),
 
 		IPRateLimit:     getEnvInt64("IP_RATE_LIMIT", 10),
 		IPRateWindow:    getEnvDuration("IP_RATE_WINDOW", "1s"),
 		IPBlockTime:     getEnvDuration("IP_BLOCK_TIME", "5m"),
+		IPPriority:      int(getEnvInt64("IP_PRIORITY", 0)),
 		TokenRateLimit:  getEnvInt64("TOKEN_RATE_LIMIT", 100),
 		TokenRateWindow: getEnvDuration("TOKEN_RATE_WINDOW", "1s"),
 		TokenBlockTime:  getEnvDuration("TOKEN_BLOCK_TIME", "5m"),
+		TokenPriority:   int(getEnvInt64("TOKEN_PRIORITY", 1)),
 
 		TokenConfigs: make(map[string]TokenConfig),
+		TierConfigs:  make(map[string]TokenConfig),
+
+		TokenTiers:     make(map[string]string),
+		TokenTiersFile: getEnvString("TOKEN_TIERS_FILE", ""),
+
+		TokenHashSecret:   getEnvString("TOKEN_HASH_SECRET", ""),
+		TokenConfigHashed: getEnvBool("TOKEN_CONFIG_HASHED", false),
+
+		JWTJWKSFile:  getEnvString("JWT_JWKS_FILE", ""),
+		JWTHeader:    getEnvString("JWT_HEADER", "Authorization"),
+		JWTKeyClaim:  getEnvString("JWT_KEY_CLAIM", "sub"),
+		JWTTierClaim: getEnvString("JWT_TIER_CLAIM", "tier"),
+		JWTIssuer:    getEnvString("JWT_ISSUER", ""),
+		JWTAudience:  getEnvString("JWT_AUDIENCE", ""),
 	}
 
 	config.loadTokenConfigs()
+	config.loadTierConfigs()
+	config.loadTokenTiers()
+
+	logLevel, err := logging.ParseLevel(getEnvString("LOG_LEVEL", "info"))
+	if err != nil {
+		return nil, err
+	}
+	config.LogLevel = logLevel
+
+	if config.LogFormat != logging.FormatText && config.LogFormat != logging.FormatJSON {
+		return nil, fmt.Errorf("invalid LOG_FORMAT %q, expected %s or %s", config.LogFormat, logging.FormatText, logging.FormatJSON)
+	}
+
+	countedStatuses, err := parseStatuses(getEnvString("COUNTED_STATUSES", ""))
+	if err != nil {
+		return nil, err
+	}
+	config.CountedStatuses = countedStatuses
+
+	trustedProxies, err := parseTrustedProxies(getEnvString("TRUSTED_PROXIES", ""))
+	if err != nil {
+		return nil, err
+	}
+	config.TrustedProxies = trustedProxies
+
+	thresholds, err := parseShedThresholds(getEnvString("SHED_THRESHOLDS", "0=0.6,1=0.85"))
+	if err != nil {
+		return nil, err
+	}
+	config.ShedThresholds = thresholds
+
+	if config.Mode != ModeDemo && config.Mode != ModeProxy {
+		return nil, fmt.Errorf("invalid MODE %q, expected %s or %s", config.Mode, ModeDemo, ModeProxy)
+	}
+
+	if config.StorageBackend != StorageRedis && config.StorageBackend != StorageBolt {
+		return nil, fmt.Errorf("invalid STORAGE_BACKEND %q, expected %s or %s", config.StorageBackend, StorageRedis, StorageBolt)
+	}
+
+	if config.Mode == ModeProxy && config.ProxyRoutes == "" {
+		return nil, fmt.Errorf("PROXY_ROUTES is required in %s mode", ModeProxy)
+	}
+
+	if config.RLSPort != "" && config.RLSRulesFile == "" {
+		return nil, fmt.Errorf("RLS_RULES_FILE is required when RLS_PORT is set")
+	}
+
+	if config.ShutdownDrainDelay < 0 {
+		return nil, fmt.Errorf("SHUTDOWN_DRAIN_DELAY must not be negative, got %v", config.ShutdownDrainDelay)
+	}
+
+	if config.BoltCompactionInterval < 0 {
+		return nil, fmt.Errorf("BOLT_COMPACTION_INTERVAL must not be negative, got %v", config.BoltCompactionInterval)
+	}
+
+	if config.StorageHealthInterval <= 0 {
+		return nil, fmt.Errorf("STORAGE_HEALTH_INTERVAL must be positive, got %v", config.StorageHealthInterval)
+	}
+
+	if config.TokenConfigHashed && config.TokenHashSecret == "" {
+		return nil, fmt.Errorf("TOKEN_HASH_SECRET is required when TOKEN_CONFIG_HASHED is set")
+	}
 
 	return config, nil
 }
@@ -63,11 +276,13 @@ func (c *Config) loadTokenConfigs() {
 					limit := getEnvInt64(fmt.Sprintf("TOKEN_%s_LIMIT", token), c.TokenRateLimit)
 					window := getEnvDuration(fmt.Sprintf("TOKEN_%s_WINDOW", token), c.TokenRateWindow.String())
 					blockTime := getEnvDuration(fmt.Sprintf("TOKEN_%s_BLOCK_TIME", token), c.TokenBlockTime.String())
+					priority := int(getEnvInt64(fmt.Sprintf("TOKEN_%s_PRIORITY", token), int64(c.TokenPriority)))
 
 					c.TokenConfigs[token] = TokenConfig{
 						Limit:     limit,
 						Window:    window,
 						BlockTime: blockTime,
+						Priority:  priority,
 					}
 				}
 			}
@@ -75,6 +290,41 @@ func (c *Config) loadTokenConfigs() {
 	}
 }
 
+func (c *Config) loadTierConfigs() {
+	for _, env := range os.Environ() {
+		name, _, _ := strings.Cut(env, "=")
+		if !strings.HasPrefix(name, "TIER_") || !strings.HasSuffix(name, "_LIMIT") {
+			continue
+		}
+
+		tier := strings.TrimSuffix(strings.TrimPrefix(name, "TIER_"), "_LIMIT")
+		if tier == "" {
+			continue
+		}
+
+		c.TierConfigs[tier] = TokenConfig{
+			Limit:     getEnvInt64(fmt.Sprintf("TIER_%s_LIMIT", tier), c.TokenRateLimit),
+			Window:    getEnvDuration(fmt.Sprintf("TIER_%s_WINDOW", tier), c.TokenRateWindow.String()),
+			BlockTime: getEnvDuration(fmt.Sprintf("TIER_%s_BLOCK_TIME", tier), c.TokenBlockTime.String()),
+			Priority:  int(getEnvInt64(fmt.Sprintf("TIER_%s_PRIORITY", tier), int64(c.TokenPriority))),
+		}
+	}
+}
+
+func (c *Config) loadTokenTiers() {
+	for _, env := range os.Environ() {
+		name, value, _ := strings.Cut(env, "=")
+		if !strings.HasPrefix(name, "TOKEN_") || !strings.HasSuffix(name, "_TIER") {
+			continue
+		}
+
+		token := strings.TrimSuffix(strings.TrimPrefix(name, "TOKEN_"), "_TIER")
+		if token != "" && value != "" {
+			c.TokenTiers[token] = value
+		}
+	}
+}
+
 func parseTokenEnvVar(env string) []string {
 	equalIndex := -1
 	for i, char := range env {
@@ -121,6 +371,7 @@ func (c *Config) GetIPConfig() ratelimiter.Config {
 		Limit:     c.IPRateLimit,
 		Window:    c.IPRateWindow,
 		BlockTime: c.IPBlockTime,
+		Priority:  c.IPPriority,
 	}
 }
 
@@ -130,6 +381,7 @@ func (c *Config) GetTokenConfig(token string) (ratelimiter.Config, bool) {
 			Limit:     tokenConfig.Limit,
 			Window:    tokenConfig.Window,
 			BlockTime: tokenConfig.BlockTime,
+			Priority:  tokenConfig.Priority,
 		}, true
 	}
 
@@ -137,9 +389,119 @@ func (c *Config) GetTokenConfig(token string) (ratelimiter.Config, bool) {
 		Limit:     c.TokenRateLimit,
 		Window:    c.TokenRateWindow,
 		BlockTime: c.TokenBlockTime,
+		Priority:  c.TokenPriority,
 	}, false
 }
 
+func (c *Config) GetAdaptiveConfig() ratelimiter.AdaptiveConfig {
+	return ratelimiter.AdaptiveConfig{
+		LatencyTarget:      c.AdaptiveLatencyTarget,
+		ErrorRateThreshold: c.AdaptiveErrorRate,
+		Interval:           c.AdaptiveInterval,
+		MinSamples:         c.AdaptiveMinSamples,
+		MinFactor:          c.AdaptiveMinFactor,
+	}
+}
+
+func (c *Config) GetBandwidthConfig() ratelimiter.BandwidthConfig {
+	return ratelimiter.BandwidthConfig{
+		Limit:          c.BandwidthLimit,
+		Window:         c.BandwidthWindow,
+		BytesPerSecond: c.BandwidthRate,
+	}
+}
+
+func (c *Config) GetSheddingConfig() ratelimiter.SheddingConfig {
+	return ratelimiter.SheddingConfig{
+		Capacity:   c.ShedCapacity,
+		Window:     c.ShedWindow,
+		Thresholds: c.ShedThresholds,
+	}
+}
+
+func parseStatuses(value string) ([]int, error) {
+	var statuses []int
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		status, err := strconv.Atoi(field)
+		if err != nil || status < 100 || status > 599 {
+			return nil, fmt.Errorf("invalid COUNTED_STATUSES entry %q, expected an HTTP status code", field)
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// parseTrustedProxies parses addresses and CIDR ranges separated by commas,
+// e.g. "10.0.0.0/8,192.168.1.10".
+func parseTrustedProxies(value string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		if addr, err := netip.ParseAddr(field); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TRUSTED_PROXIES entry %q, expected an IP address or CIDR range", field)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// parseShedThresholds parses "priority=fraction" pairs separated by commas,
+// e.g. "0=0.6,1=0.85".
+func parseShedThresholds(value string) (map[int]float64, error) {
+	thresholds := make(map[int]float64)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		priority, fraction, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid SHED_THRESHOLDS entry %q, expected priority=fraction", pair)
+		}
+
+		p, err := strconv.Atoi(strings.TrimSpace(priority))
+		if err != nil {
+			return nil, fmt.Errorf("invalid SHED_THRESHOLDS priority %q: %w", priority, err)
+		}
+
+		f, err := strconv.ParseFloat(strings.TrimSpace(fraction), 64)
+		if err != nil || f < 0 || f > 1 {
+			return nil, fmt.Errorf("invalid SHED_THRESHOLDS fraction %q, expected a number between 0 and 1", fraction)
+		}
+
+		thresholds[p] = f
+	}
+	return thresholds, nil
+}
+
+func (c *Config) GetTierConfigs() map[string]ratelimiter.Config {
+	configs := make(map[string]ratelimiter.Config, len(c.TierConfigs))
+	for tier, tierConfig := range c.TierConfigs {
+		configs[tier] = ratelimiter.Config{
+			Limit:     tierConfig.Limit,
+			Window:    tierConfig.Window,
+			BlockTime: tierConfig.BlockTime,
+			Priority:  tierConfig.Priority,
+		}
+	}
+	return configs
+}
 
 func getEnvString(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -157,6 +519,24 @@ func getEnvInt64(key string, defaultValue int64) int64 {
 	return defaultValue
 }
 
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
+
 func getEnvDuration(key string, defaultValue string) time.Duration {
 	value := getEnvString(key, defaultValue)
 	if duration, err := time.ParseDuration(value); err == nil {
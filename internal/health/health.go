@@ -0,0 +1,90 @@
+// Package health serves the liveness and readiness endpoints of the server.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// Pinger reports whether a dependency, such as the storage, is reachable.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+type Status struct {
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Checker serves /livez, which only reports that the process is up, and
+// /readyz, which fails while the storage is unreachable or the server is
+// draining, so load balancers stop sending traffic before shutdown.
+type Checker struct {
+	pinger   Pinger
+	timeout  time.Duration
+	draining atomic.Bool
+}
+
+func NewChecker(pinger Pinger, timeout time.Duration) *Checker {
+	return &Checker{
+		pinger:  pinger,
+		timeout: timeout,
+	}
+}
+
+// SetDraining makes readiness fail from now on.
+func (c *Checker) SetDraining() {
+	c.draining.Store(true)
+}
+
+// Drain makes readiness fail and then waits for delay, or until ctx is done,
+// while the server keeps serving, so load balancers see the failing probe
+// and stop sending traffic before the listener closes.
+func (c *Checker) Drain(ctx context.Context, delay time.Duration) {
+	c.SetDraining()
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+func (c *Checker) Livez(w http.ResponseWriter, r *http.Request) {
+	writeStatus(w, http.StatusOK, "ok", "")
+}
+
+func (c *Checker) Readyz(w http.ResponseWriter, r *http.Request) {
+	if c.draining.Load() {
+		writeStatus(w, http.StatusServiceUnavailable, "draining", "")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), c.timeout)
+	defer cancel()
+
+	if err := c.pinger.Ping(ctx); err != nil {
+		writeStatus(w, http.StatusServiceUnavailable, "unavailable", "storage: "+err.Error())
+		return
+	}
+
+	writeStatus(w, http.StatusOK, "ok", "")
+}
+
+func writeStatus(w http.ResponseWriter, code int, status, errMessage string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+
+	json.NewEncoder(w).Encode(Status{
+		Status:    status,
+		Error:     errMessage,
+		Timestamp: time.Now(),
+	})
+}
@@ -0,0 +1,74 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type pingerFunc func(ctx context.Context) error
+
+func (f pingerFunc) Ping(ctx context.Context) error {
+	return f(ctx)
+}
+
+func serve(handler http.HandlerFunc) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	handler(recorder, httptest.NewRequest("GET", "/", nil))
+	return recorder
+}
+
+func TestChecker_Readyz(t *testing.T) {
+	var pingErr error
+	checker := NewChecker(pingerFunc(func(ctx context.Context) error { return pingErr }), time.Second)
+
+	assert.Equal(t, http.StatusOK, serve(checker.Readyz).Code)
+
+	pingErr = errors.New("connection refused")
+	recorder := serve(checker.Readyz)
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "connection refused")
+
+	assert.Equal(t, http.StatusOK, serve(checker.Livez).Code)
+}
+
+func TestChecker_Draining(t *testing.T) {
+	checker := NewChecker(pingerFunc(func(ctx context.Context) error { return nil }), time.Second)
+
+	checker.SetDraining()
+
+	recorder := serve(checker.Readyz)
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "draining")
+	assert.Equal(t, http.StatusOK, serve(checker.Livez).Code)
+}
+
+func TestChecker_Drain(t *testing.T) {
+	checker := NewChecker(pingerFunc(func(ctx context.Context) error { return nil }), time.Second)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		checker.Drain(ctx, time.Minute)
+	}()
+
+	assert.Eventually(t, func() bool {
+		return serve(checker.Readyz).Code == http.StatusServiceUnavailable
+	}, time.Second, time.Millisecond)
+
+	select {
+	case <-done:
+		t.Fatal("Drain returned before its delay")
+	default:
+	}
+
+	cancel()
+	<-done
+}
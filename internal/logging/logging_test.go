@@ -0,0 +1,43 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLevel(t *testing.T) {
+	level, err := ParseLevel("warn")
+	require.NoError(t, err)
+	assert.Equal(t, slog.LevelWarn, level)
+
+	level, err = ParseLevel("DEBUG")
+	require.NoError(t, err)
+	assert.Equal(t, slog.LevelDebug, level)
+
+	_, err = ParseLevel("verbose")
+	assert.Error(t, err)
+}
+
+func TestNew_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, slog.LevelInfo, FormatJSON)
+	require.NoError(t, err)
+
+	logger.Debug("hidden")
+	logger.Info("shown", "key", "ip:1.2.3.4")
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "shown", entry["msg"])
+	assert.Equal(t, "ip:1.2.3.4", entry["key"])
+}
+
+func TestNew_InvalidFormat(t *testing.T) {
+	_, err := New(&bytes.Buffer{}, slog.LevelInfo, "xml")
+	assert.Error(t, err)
+}
@@ -0,0 +1,159 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/netip"
+	"net/url"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/tiago-kimura/rate-limiter/pkg/middleware"
+)
+
+type Route struct {
+	Prefix    string
+	Upstreams []*url.URL
+}
+
+// ParseRoutes parses routes in the form
+// "/api=http://a:8080,http://b:8080;/=http://legacy:8080", where requests
+// matching a prefix are balanced round-robin across its upstreams.
+func ParseRoutes(spec string) ([]Route, error) {
+	var routes []Route
+
+	for _, entry := range strings.Split(spec, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		prefix, targets, ok := strings.Cut(entry, "=")
+		prefix = strings.TrimSpace(prefix)
+		if !ok || !strings.HasPrefix(prefix, "/") {
+			return nil, fmt.Errorf("invalid proxy route %q", entry)
+		}
+
+		route := Route{Prefix: prefix}
+		for _, target := range strings.Split(targets, ",") {
+			upstream, err := url.Parse(strings.TrimSpace(target))
+			if err != nil || upstream.Scheme == "" || upstream.Host == "" {
+				return nil, fmt.Errorf("invalid upstream %q for route %s", target, prefix)
+			}
+			route.Upstreams = append(route.Upstreams, upstream)
+		}
+
+		routes = append(routes, route)
+	}
+
+	if len(routes) == 0 {
+		return nil, errors.New("no proxy routes configured")
+	}
+
+	return routes, nil
+}
+
+type Proxy struct {
+	routes  []*routeProxy
+	trusted []netip.Prefix
+}
+
+type Option func(*Proxy)
+
+// WithTrustedProxies keeps the X-Forwarded-For and X-Real-IP headers of
+// requests from the given proxies. Those of other clients are dropped, so
+// upstreams cannot be handed a spoofed address.
+func WithTrustedProxies(prefixes ...netip.Prefix) Option {
+	return func(p *Proxy) {
+		p.trusted = prefixes
+	}
+}
+
+type routeProxy struct {
+	prefix  string
+	proxies []*httputil.ReverseProxy
+	next    atomic.Uint64
+}
+
+// New returns a handler forwarding each request to the route with the longest
+// matching prefix. Responses are streamed to the client as they arrive and
+// upstreams that don't send response headers within timeout fail with 504.
+func New(routes []Route, timeout time.Duration, opts ...Option) (*Proxy, error) {
+	if len(routes) == 0 {
+		return nil, errors.New("no proxy routes configured")
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second}).DialContext
+	transport.ResponseHeaderTimeout = timeout
+
+	p := &Proxy{}
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	for _, route := range routes {
+		if len(route.Upstreams) == 0 {
+			return nil, fmt.Errorf("route %s has no upstreams", route.Prefix)
+		}
+
+		rp := &routeProxy{prefix: route.Prefix}
+		for _, upstream := range route.Upstreams {
+			rp.proxies = append(rp.proxies, newReverseProxy(upstream, transport, p.trusted))
+		}
+		p.routes = append(p.routes, rp)
+	}
+
+	sort.SliceStable(p.routes, func(i, j int) bool {
+		return len(p.routes[i].prefix) > len(p.routes[j].prefix)
+	})
+
+	return p, nil
+}
+
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	for _, route := range p.routes {
+		if strings.HasPrefix(r.URL.Path, route.prefix) {
+			n := route.next.Add(1) - 1
+			route.proxies[n%uint64(len(route.proxies))].ServeHTTP(w, r)
+			return
+		}
+	}
+
+	http.NotFound(w, r)
+}
+
+func newReverseProxy(upstream *url.URL, transport http.RoundTripper, trusted []netip.Prefix) *httputil.ReverseProxy {
+	return &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(upstream)
+			if middleware.FromTrustedProxy(pr.In, trusted...) {
+				// Rewrite drops the incoming X-Forwarded-For, SetXForwarded
+				// appends to the copied one.
+				pr.Out.Header["X-Forwarded-For"] = pr.In.Header["X-Forwarded-For"]
+			} else {
+				pr.Out.Header.Del("X-Real-IP")
+			}
+			pr.SetXForwarded()
+		},
+		Transport:     transport,
+		FlushInterval: -1,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			slog.WarnContext(r.Context(), "upstream failed", "upstream", upstream.Host, "error", err)
+
+			status := http.StatusBadGateway
+			var netErr net.Error
+			if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+				status = http.StatusGatewayTimeout
+			}
+			w.WriteHeader(status)
+		},
+	}
+}
@@ -0,0 +1,173 @@
+package proxy
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func upstream(t *testing.T, name string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Upstream", name)
+		w.Header().Set("X-Seen-Forwarded-For", r.Header.Get("X-Forwarded-For"))
+		w.Header().Set("X-Seen-Real-IP", r.Header.Get("X-Real-IP"))
+		w.Header().Set("X-Seen-Custom", r.Header.Get("X-Custom"))
+		io.WriteString(w, r.URL.Path)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func mustParse(t *testing.T, raw string) *url.URL {
+	u, err := url.Parse(raw)
+	require.NoError(t, err)
+	return u
+}
+
+func TestParseRoutes(t *testing.T) {
+	routes, err := ParseRoutes("/api=http://a:8080, http://b:8080; /=http://legacy:9000")
+	require.NoError(t, err)
+	require.Len(t, routes, 2)
+	assert.Equal(t, "/api", routes[0].Prefix)
+	assert.Len(t, routes[0].Upstreams, 2)
+	assert.Equal(t, "b:8080", routes[0].Upstreams[1].Host)
+	assert.Equal(t, "/", routes[1].Prefix)
+
+	for _, spec := range []string{"", "api=http://a", "/api=a:8080", "/api"} {
+		_, err := ParseRoutes(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestProxy_LongestPrefixAndRoundRobin(t *testing.T) {
+	a := upstream(t, "a")
+	b := upstream(t, "b")
+	legacy := upstream(t, "legacy")
+
+	p, err := New([]Route{
+		{Prefix: "/", Upstreams: []*url.URL{mustParse(t, legacy.URL)}},
+		{Prefix: "/api", Upstreams: []*url.URL{mustParse(t, a.URL), mustParse(t, b.URL)}},
+	}, time.Second)
+	require.NoError(t, err)
+
+	var seen []string
+	for i := 0; i < 4; i++ {
+		recorder := httptest.NewRecorder()
+		p.ServeHTTP(recorder, httptest.NewRequest("GET", "/api/items", nil))
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "/api/items", recorder.Body.String())
+		seen = append(seen, recorder.Header().Get("X-Upstream"))
+	}
+	assert.Equal(t, []string{"a", "b", "a", "b"}, seen)
+
+	recorder := httptest.NewRecorder()
+	p.ServeHTTP(recorder, httptest.NewRequest("GET", "/other", nil))
+	assert.Equal(t, "legacy", recorder.Header().Get("X-Upstream"))
+}
+
+func TestProxy_ForwardsHeaders(t *testing.T) {
+	a := upstream(t, "a")
+
+	p, err := New([]Route{{Prefix: "/", Upstreams: []*url.URL{mustParse(t, a.URL)}}}, time.Second)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "203.0.113.7:4567"
+	req.Header.Set("X-Custom", "value")
+
+	recorder := httptest.NewRecorder()
+	p.ServeHTTP(recorder, req)
+
+	assert.Equal(t, "203.0.113.7", recorder.Header().Get("X-Seen-Forwarded-For"))
+	assert.Equal(t, "value", recorder.Header().Get("X-Seen-Custom"))
+}
+
+func TestProxy_ForwardedForOnlyFromTrustedProxies(t *testing.T) {
+	a := upstream(t, "a")
+
+	p, err := New([]Route{{Prefix: "/", Upstreams: []*url.URL{mustParse(t, a.URL)}}}, time.Second,
+		WithTrustedProxies(netip.MustParsePrefix("10.0.0.0/8")))
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		remoteAddr string
+		expected   string
+		realIP     string
+	}{
+		{"203.0.113.7:4567", "203.0.113.7", ""},
+		{"10.0.0.1:4567", "198.51.100.1, 10.0.0.1", "198.51.100.1"},
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = tc.remoteAddr
+		req.Header.Set("X-Forwarded-For", "198.51.100.1")
+		req.Header.Set("X-Real-IP", "198.51.100.1")
+
+		recorder := httptest.NewRecorder()
+		p.ServeHTTP(recorder, req)
+
+		assert.Equal(t, tc.expected, recorder.Header().Get("X-Seen-Forwarded-For"), tc.remoteAddr)
+		assert.Equal(t, tc.realIP, recorder.Header().Get("X-Seen-Real-IP"), tc.remoteAddr)
+	}
+}
+
+func TestProxy_Streaming(t *testing.T) {
+	release := make(chan struct{})
+	streaming := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "first\n")
+		w.(http.Flusher).Flush()
+		<-release
+		io.WriteString(w, "second\n")
+	}))
+	defer streaming.Close()
+
+	p, err := New([]Route{{Prefix: "/", Upstreams: []*url.URL{mustParse(t, streaming.URL)}}}, time.Second)
+	require.NoError(t, err)
+
+	front := httptest.NewServer(p)
+	defer front.Close()
+
+	resp, err := http.Get(front.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "first\n", line)
+
+	close(release)
+	line, err = reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "second\n", line)
+}
+
+func TestProxy_UpstreamTimeout(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer slow.Close()
+
+	p, err := New([]Route{{Prefix: "/", Upstreams: []*url.URL{mustParse(t, slow.URL)}}}, 20*time.Millisecond)
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	p.ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusGatewayTimeout, recorder.Code)
+}
+
+func TestProxy_UnreachableUpstream(t *testing.T) {
+	p, err := New([]Route{{Prefix: "/", Upstreams: []*url.URL{mustParse(t, "http://127.0.0.1:1")}}}, time.Second)
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	p.ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusBadGateway, recorder.Code)
+}
@@ -1,67 +0,0 @@
-package storage
-
-import (
-	"context"
-	"time"
-
-	"github.com/go-redis/redis/v8"
-)
-
-type RedisStorage struct {
-	client *redis.Client
-}
-
-func NewRedisStorage(redisURL string) (*RedisStorage, error) {
-	opt, err := redis.ParseURL(redisURL)
-	if err != nil {
-		return nil, err
-	}
-
-	client := redis.NewClient(opt)
-
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	if err := client.Ping(ctx).Err(); err != nil {
-		return nil, err
-	}
-
-	return &RedisStorage{
-		client: client,
-	}, nil
-}
-
-func (r *RedisStorage) Get(ctx context.Context, key string) (int64, error) {
-	val, err := r.client.Get(ctx, key).Int64()
-	if err == redis.Nil {
-		return 0, nil
-	}
-	return val, err
-}
-
-func (r *RedisStorage) Increment(ctx context.Context, key string, expiration time.Duration) (int64, error) {
-	pipe := r.client.TxPipeline()
-
-	incr := pipe.Incr(ctx, key)
-
-	pipe.Expire(ctx, key, expiration)
-
-	_, err := pipe.Exec(ctx)
-	if err != nil {
-		return 0, err
-	}
-
-	return incr.Val(), nil
-}
-
-func (r *RedisStorage) Set(ctx context.Context, key string, count int64, expiration time.Duration) error {
-	return r.client.Set(ctx, key, count, expiration).Err()
-}
-
-func (r *RedisStorage) TTL(ctx context.Context, key string) (time.Duration, error) {
-	return r.client.TTL(ctx, key).Result()
-}
-
-func (r *RedisStorage) Close() error {
-	return r.client.Close()
-}
@@ -0,0 +1,194 @@
+package checkapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tiago-kimura/rate-limiter/pkg/ratelimiter"
+)
+
+var (
+	ErrNoKeys        = errors.New("keys are required")
+	ErrInvalidCost   = errors.New("cost must not be negative")
+	ErrUnknownPolicy = errors.New("unknown policy")
+)
+
+// Handler serves the generic check API for workloads that are not HTTP
+// requests, such as queue consumers and cron jobs.
+type Handler struct {
+	rateLimiter *ratelimiter.RateLimiter
+	timeout     time.Duration
+}
+
+func NewHandler(rateLimiter *ratelimiter.RateLimiter) *Handler {
+	return &Handler{
+		rateLimiter: rateLimiter,
+		timeout:     5 * time.Second,
+	}
+}
+
+func (h *Handler) CheckHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		var req CheckRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON body")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), h.timeout)
+		defer cancel()
+
+		response, err := h.check(ctx, req)
+		if err != nil {
+			writeError(w, statusFor(err), err.Error())
+			return
+		}
+
+		writeJSON(w, http.StatusOK, response)
+	})
+}
+
+func (h *Handler) BatchCheckHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		var req BatchCheckRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON body")
+			return
+		}
+
+		if len(req.Checks) > MaxBatchSize {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("at most %d checks per batch", MaxBatchSize))
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), h.timeout)
+		defer cancel()
+
+		response := BatchCheckResponse{Results: make([]CheckResponse, len(req.Checks))}
+
+		var checks []ratelimiter.LimitCheck
+		var indexes []int
+		for i, check := range req.Checks {
+			limitCheck, err := h.limitCheck(check)
+			if err != nil {
+				response.Results[i] = CheckResponse{Error: err.Error()}
+				continue
+			}
+			checks = append(checks, limitCheck)
+			indexes = append(indexes, i)
+		}
+
+		// A failed check does not fail the batch, since the others have
+		// already been counted.
+		for j, res := range h.rateLimiter.CheckLimits(ctx, checks) {
+			if res.Err != nil {
+				response.Results[indexes[j]] = CheckResponse{Error: res.Err.Error()}
+				continue
+			}
+			response.Results[indexes[j]] = checkResponse(res.Result)
+		}
+
+		writeJSON(w, http.StatusOK, response)
+	})
+}
+
+func (h *Handler) check(ctx context.Context, req CheckRequest) (*CheckResponse, error) {
+	limitCheck, err := h.limitCheck(req)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := h.rateLimiter.CheckN(ctx, limitCheck.Cost, limitCheck.Identities...)
+	if err != nil {
+		return nil, err
+	}
+
+	response := checkResponse(result)
+	return &response, nil
+}
+
+func (h *Handler) limitCheck(req CheckRequest) (ratelimiter.LimitCheck, error) {
+	if len(req.Keys) == 0 {
+		return ratelimiter.LimitCheck{}, ErrNoKeys
+	}
+	if req.Cost < 0 {
+		return ratelimiter.LimitCheck{}, ErrInvalidCost
+	}
+	if _, exists := h.rateLimiter.TierConfig(req.Policy); !exists {
+		return ratelimiter.LimitCheck{}, fmt.Errorf("%w %q", ErrUnknownPolicy, req.Policy)
+	}
+
+	cost := req.Cost
+	if cost == 0 {
+		cost = 1
+	}
+
+	return ratelimiter.LimitCheck{
+		Cost: cost,
+		Identities: []ratelimiter.Identity{{
+			Type:  ratelimiter.KeySetLimit,
+			Value: keySetValue(req.Policy, req.Keys),
+			Tier:  req.Policy,
+		}},
+	}, nil
+}
+
+func checkResponse(result *ratelimiter.CheckResult) CheckResponse {
+	return CheckResponse{
+		Allowed:   result.Allowed,
+		Limit:     result.Limit,
+		Remaining: result.Remaining,
+		Reset:     result.ResetTime,
+	}
+}
+
+// keySetValue builds a stable identity value from the policy and the sorted
+// keys, so the same key set always maps to the same counter and different
+// key sets never do.
+func keySetValue(policy string, keys map[string]string) string {
+	names := make([]string, 0, len(keys))
+	for name := range keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names)+1)
+	parts = append(parts, ratelimiter.EscapeKeyPart(policy))
+	for _, name := range names {
+		parts = append(parts, ratelimiter.EscapeKeyPart(name)+"="+ratelimiter.EscapeKeyPart(keys[name]))
+	}
+	return strings.Join(parts, "|")
+}
+
+func statusFor(err error) int {
+	if errors.Is(err, ErrNoKeys) || errors.Is(err, ErrInvalidCost) || errors.Is(err, ErrUnknownPolicy) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, ErrorResponse{Error: message})
+}
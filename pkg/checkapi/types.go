@@ -0,0 +1,38 @@
+package checkapi
+
+import "time"
+
+const (
+	CheckPath      = "/v1/check"
+	BatchCheckPath = "/v1/check/batch"
+	MaxBatchSize   = 100
+)
+
+// CheckRequest asks whether the action identified by Keys may proceed. The
+// keys are limited together, so {"user": "42", "action": "export"} has its
+// own counter. Cost defaults to 1 and Policy names the limit tier to apply.
+type CheckRequest struct {
+	Keys   map[string]string `json:"keys"`
+	Cost   int64             `json:"cost,omitempty"`
+	Policy string            `json:"policy"`
+}
+
+type CheckResponse struct {
+	Allowed   bool      `json:"allowed"`
+	Limit     int64     `json:"limit"`
+	Remaining int64     `json:"remaining"`
+	Reset     time.Time `json:"reset"`
+	Error     string    `json:"error,omitempty"`
+}
+
+type BatchCheckRequest struct {
+	Checks []CheckRequest `json:"checks"`
+}
+
+type BatchCheckResponse struct {
+	Results []CheckResponse `json:"results"`
+}
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
@@ -0,0 +1,91 @@
+package checkclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/tiago-kimura/rate-limiter/pkg/checkapi"
+)
+
+// Client calls the generic check API of a rate limiter server.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+type Option func(*Client)
+
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Check asks whether the action identified by keys may proceed under policy,
+// counting cost units against the limit.
+func (c *Client) Check(ctx context.Context, policy string, keys map[string]string, cost int64) (*checkapi.CheckResponse, error) {
+	var response checkapi.CheckResponse
+	err := c.post(ctx, checkapi.CheckPath, checkapi.CheckRequest{Keys: keys, Cost: cost, Policy: policy}, &response)
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// CheckBatch checks many key sets in one call. Results are in request order;
+// invalid and failed checks have their Error field set.
+func (c *Client) CheckBatch(ctx context.Context, checks []checkapi.CheckRequest) ([]checkapi.CheckResponse, error) {
+	var response checkapi.BatchCheckResponse
+	err := c.post(ctx, checkapi.BatchCheckPath, checkapi.BatchCheckRequest{Checks: checks}, &response)
+	if err != nil {
+		return nil, err
+	}
+	return response.Results, nil
+}
+
+func (c *Client) post(ctx context.Context, path string, body, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr checkapi.ErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&apiErr); err == nil && apiErr.Error != "" {
+			return fmt.Errorf("check API returned %d: %s", resp.StatusCode, apiErr.Error)
+		}
+		return fmt.Errorf("check API returned %d", resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,174 @@
+package checkclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tiago-kimura/rate-limiter/pkg/checkapi"
+	"github.com/tiago-kimura/rate-limiter/pkg/ratelimiter"
+	"github.com/tiago-kimura/rate-limiter/pkg/storage"
+)
+
+// failingStorage fails every call on keys containing "fail".
+type failingStorage struct {
+	storage.Storage
+}
+
+func (s failingStorage) Get(ctx context.Context, key string) (int64, error) {
+	if strings.Contains(key, "fail") {
+		return 0, errors.New("storage unavailable")
+	}
+	return s.Storage.Get(ctx, key)
+}
+
+func (s failingStorage) IncrementBy(ctx context.Context, key string, value int64, expiration time.Duration) (int64, error) {
+	if strings.Contains(key, "fail") {
+		return 0, errors.New("storage unavailable")
+	}
+	return s.Storage.IncrementBy(ctx, key, value, expiration)
+}
+
+func newTestClient(t *testing.T) (*Client, *httptest.Server) {
+	return newTestClientWithStorage(t, storage.NewMockStorage())
+}
+
+func newTestClientWithStorage(t *testing.T, store storage.Storage) (*Client, *httptest.Server) {
+	rateLimiter := ratelimiter.NewRateLimiter(store,
+		ratelimiter.Config{Limit: 100, Window: time.Second, BlockTime: time.Minute},
+		ratelimiter.WithTierConfig("exports", ratelimiter.Config{Limit: 5, Window: time.Minute, BlockTime: time.Minute}),
+	)
+	handler := checkapi.NewHandler(rateLimiter)
+
+	mux := http.NewServeMux()
+	mux.Handle(checkapi.CheckPath, handler.CheckHandler())
+	mux.Handle(checkapi.BatchCheckPath, handler.BatchCheckHandler())
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return New(server.URL), server
+}
+
+func TestClient_Check(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+	keys := map[string]string{"tenant": "acme", "action": "export"}
+
+	response, err := client.Check(ctx, "exports", keys, 3)
+	require.NoError(t, err)
+	assert.True(t, response.Allowed)
+	assert.Equal(t, int64(5), response.Limit)
+	assert.Equal(t, int64(2), response.Remaining)
+	assert.True(t, response.Reset.After(time.Now()))
+
+	response, err = client.Check(ctx, "exports", map[string]string{"action": "export", "tenant": "acme"}, 0)
+	require.NoError(t, err)
+	assert.True(t, response.Allowed)
+	assert.Equal(t, int64(1), response.Remaining)
+
+	response, err = client.Check(ctx, "exports", keys, 2)
+	require.NoError(t, err)
+	assert.False(t, response.Allowed)
+	assert.Equal(t, int64(0), response.Remaining)
+
+	response, err = client.Check(ctx, "exports", map[string]string{"tenant": "globex", "action": "export"}, 1)
+	require.NoError(t, err)
+	assert.True(t, response.Allowed)
+}
+
+func TestClient_CheckKeySetsDoNotCollide(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	response, err := client.Check(ctx, "exports", map[string]string{"a": "1|b=2"}, 5)
+	require.NoError(t, err)
+	assert.True(t, response.Allowed)
+
+	response, err = client.Check(ctx, "exports", map[string]string{"a": "1", "b": "2"}, 1)
+	require.NoError(t, err)
+	assert.True(t, response.Allowed)
+	assert.Equal(t, int64(4), response.Remaining)
+}
+
+func TestClient_CheckErrors(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	_, err := client.Check(ctx, "missing", map[string]string{"user": "42"}, 1)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown policy")
+
+	_, err = client.Check(ctx, "exports", nil, 1)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "keys are required")
+
+	_, err = client.Check(ctx, "exports", map[string]string{"user": "42"}, -1)
+	assert.Error(t, err)
+}
+
+func TestClient_CheckBatch(t *testing.T) {
+	client, _ := newTestClient(t)
+
+	results, err := client.CheckBatch(context.Background(), []checkapi.CheckRequest{
+		{Keys: map[string]string{"user": "1"}, Cost: 5, Policy: "exports"},
+		{Keys: map[string]string{"user": "1"}, Policy: "exports"},
+		{Keys: map[string]string{"user": "2"}, Policy: "exports"},
+		{Keys: map[string]string{"user": "3"}, Policy: "missing"},
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 4)
+
+	assert.True(t, results[0].Allowed)
+	assert.False(t, results[1].Allowed)
+	assert.True(t, results[2].Allowed)
+	assert.False(t, results[3].Allowed)
+	assert.Contains(t, results[3].Error, "unknown policy")
+}
+
+func TestClient_CheckBatchFailedCheck(t *testing.T) {
+	client, _ := newTestClientWithStorage(t, failingStorage{storage.NewMockStorage()})
+	ctx := context.Background()
+
+	results, err := client.CheckBatch(ctx, []checkapi.CheckRequest{
+		{Keys: map[string]string{"user": "1"}, Cost: 5, Policy: "exports"},
+		{Keys: map[string]string{"user": "fail"}, Policy: "exports"},
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	assert.True(t, results[0].Allowed)
+	assert.Empty(t, results[0].Error)
+	assert.Contains(t, results[1].Error, "storage unavailable")
+
+	response, err := client.Check(ctx, "exports", map[string]string{"user": "1"}, 1)
+	require.NoError(t, err)
+	assert.False(t, response.Allowed)
+}
+
+func TestHandler_RejectsInvalidRequests(t *testing.T) {
+	_, server := newTestClient(t)
+
+	resp, err := http.Get(server.URL + checkapi.CheckPath)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+
+	resp, err = http.Post(server.URL+checkapi.CheckPath, "application/json", strings.NewReader("{"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	checks := strings.Repeat(`{"keys":{"a":"b"},"policy":"exports"},`, checkapi.MaxBatchSize+1)
+	body := `{"checks":[` + strings.TrimSuffix(checks, ",") + `]}`
+	resp, err = http.Post(server.URL+checkapi.BatchCheckPath, "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
@@ -0,0 +1,95 @@
+package grpclimit
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"github.com/tiago-kimura/rate-limiter/pkg/ratelimiter"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+const DefaultAPIKeyMetadata = "api-key"
+
+// KeyExtractor resolves the identities an RPC can be limited by, in order of
+// precedence, from its incoming context and full method name.
+type KeyExtractor interface {
+	Extract(ctx context.Context, fullMethod string) []ratelimiter.Identity
+}
+
+type KeyExtractorFunc func(ctx context.Context, fullMethod string) []ratelimiter.Identity
+
+func (f KeyExtractorFunc) Extract(ctx context.Context, fullMethod string) []ratelimiter.Identity {
+	return f(ctx, fullMethod)
+}
+
+var DefaultKeyExtractor = FirstOf(MetadataToken(DefaultAPIKeyMetadata), PeerIP())
+
+// PeerIP extracts the caller IP, preferring the first x-forwarded-for entry
+// set by a proxy over the peer address.
+func PeerIP() KeyExtractor {
+	return KeyExtractorFunc(func(ctx context.Context, fullMethod string) []ratelimiter.Identity {
+		if forwarded := firstMetadata(ctx, "x-forwarded-for"); forwarded != "" {
+			ip, _, _ := strings.Cut(forwarded, ",")
+			return single(ratelimiter.IPLimit, strings.TrimSpace(ip))
+		}
+
+		p, ok := peer.FromContext(ctx)
+		if !ok || p.Addr == nil {
+			return nil
+		}
+
+		ip, _, err := net.SplitHostPort(p.Addr.String())
+		if err != nil {
+			return single(ratelimiter.IPLimit, p.Addr.String())
+		}
+		return single(ratelimiter.IPLimit, ip)
+	})
+}
+
+func MetadataToken(key string) KeyExtractor {
+	return KeyExtractorFunc(func(ctx context.Context, fullMethod string) []ratelimiter.Identity {
+		return single(ratelimiter.TokenLimit, firstMetadata(ctx, key))
+	})
+}
+
+func Metadata(key string) KeyExtractor {
+	return KeyExtractorFunc(func(ctx context.Context, fullMethod string) []ratelimiter.Identity {
+		value := firstMetadata(ctx, key)
+		if value == "" {
+			return nil
+		}
+		return single(ratelimiter.HeaderLimit, key+"="+value)
+	})
+}
+
+func FirstOf(extractors ...KeyExtractor) KeyExtractor {
+	return KeyExtractorFunc(func(ctx context.Context, fullMethod string) []ratelimiter.Identity {
+		var ids []ratelimiter.Identity
+		for _, extractor := range extractors {
+			ids = append(ids, extractor.Extract(ctx, fullMethod)...)
+		}
+		return ids
+	})
+}
+
+func firstMetadata(ctx context.Context, key string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	values := md.Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func single(limitType ratelimiter.LimitType, value string) []ratelimiter.Identity {
+	if value == "" {
+		return nil
+	}
+	return []ratelimiter.Identity{{Type: limitType, Value: value}}
+}
@@ -0,0 +1,96 @@
+package grpclimit
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/tiago-kimura/rate-limiter/pkg/ratelimiter"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+type Limiter struct {
+	rateLimiter  *ratelimiter.RateLimiter
+	keyExtractor KeyExtractor
+}
+
+type Option func(*Limiter)
+
+func WithKeyExtractor(extractor KeyExtractor) Option {
+	return func(l *Limiter) {
+		l.keyExtractor = extractor
+	}
+}
+
+func New(rateLimiter *ratelimiter.RateLimiter, opts ...Option) *Limiter {
+	l := &Limiter{
+		rateLimiter:  rateLimiter,
+		keyExtractor: DefaultKeyExtractor,
+	}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	return l
+}
+
+// UnaryServerInterceptor rejects RPCs over the limit with
+// codes.ResourceExhausted and a RetryInfo detail, and RPCs the key extractor
+// finds no identity in with codes.InvalidArgument.
+func (l *Limiter) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := l.check(ctx, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor checks the limit once when a stream is opened.
+func (l *Limiter) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := l.check(ss.Context(), info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+func (l *Limiter) check(ctx context.Context, fullMethod string) error {
+	result, err := l.rateLimiter.Check(ctx, l.keyExtractor.Extract(ctx, fullMethod)...)
+	if errors.Is(err, ratelimiter.ErrNoIdentity) {
+		return status.Error(codes.InvalidArgument, "no identity to apply a rate limit to")
+	}
+	if err != nil {
+		return status.Error(codes.Internal, "rate limit check failed")
+	}
+
+	grpc.SetHeader(ctx, metadata.Pairs(
+		"x-ratelimit-limit", strconv.FormatInt(result.Limit, 10),
+		"x-ratelimit-remaining", strconv.FormatInt(result.Remaining, 10),
+		"x-ratelimit-reset", strconv.FormatInt(result.ResetTime.Unix(), 10),
+		"x-ratelimit-type", string(result.LimitType),
+	))
+
+	if result.Allowed {
+		return nil
+	}
+
+	retryDelay := result.ResetTime.Sub(l.rateLimiter.Clock().Now())
+	if retryDelay < 0 {
+		retryDelay = 0
+	}
+
+	st := status.New(codes.ResourceExhausted, "you have reached the maximum number of requests or actions allowed within a certain time frame")
+	detailed, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(retryDelay)})
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
@@ -0,0 +1,155 @@
+package grpclimit
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tiago-kimura/rate-limiter/pkg/clock"
+	"github.com/tiago-kimura/rate-limiter/pkg/ratelimiter"
+	"github.com/tiago-kimura/rate-limiter/pkg/storage"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newHealthClient(t *testing.T, opts ...Option) healthpb.HealthClient {
+	rateLimiter := ratelimiter.NewRateLimiter(storage.NewMockStorage(),
+		ratelimiter.Config{Limit: 2, Window: time.Minute, BlockTime: time.Minute},
+		ratelimiter.WithTokenConfig("abc123", ratelimiter.Config{Limit: 3, Window: time.Minute, BlockTime: time.Minute}),
+	)
+	return serveHealth(t, rateLimiter, opts...)
+}
+
+func serveHealth(t *testing.T, rateLimiter *ratelimiter.RateLimiter, opts ...Option) healthpb.HealthClient {
+	limiter := New(rateLimiter, opts...)
+
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(limiter.UnaryServerInterceptor()),
+		grpc.StreamInterceptor(limiter.StreamServerInterceptor()),
+	)
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return healthpb.NewHealthClient(conn)
+}
+
+func TestUnaryInterceptor_PeerIP(t *testing.T) {
+	client := newHealthClient(t)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		var header metadata.MD
+		_, err := client.Check(ctx, &healthpb.HealthCheckRequest{}, grpc.Header(&header))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"2"}, header.Get("x-ratelimit-limit"))
+		assert.Equal(t, []string{"ip"}, header.Get("x-ratelimit-type"))
+	}
+
+	_, err := client.Check(ctx, &healthpb.HealthCheckRequest{})
+	require.Error(t, err)
+
+	st := status.Convert(err)
+	assert.Equal(t, codes.ResourceExhausted, st.Code())
+	require.Len(t, st.Details(), 1)
+	retryInfo, ok := st.Details()[0].(*errdetails.RetryInfo)
+	require.True(t, ok)
+	assert.True(t, retryInfo.RetryDelay.AsDuration() > 0)
+}
+
+func TestUnaryInterceptor_RetryDelayFromLimiterClock(t *testing.T) {
+	fake := clock.NewFake(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC))
+	rateLimiter := ratelimiter.NewRateLimiter(storage.NewMockStorage(storage.WithMockClock(fake)),
+		ratelimiter.Config{Limit: 1, Window: time.Minute, BlockTime: 5 * time.Minute},
+		ratelimiter.WithClock(fake),
+	)
+	client := serveHealth(t, rateLimiter)
+	ctx := context.Background()
+
+	_, err := client.Check(ctx, &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	_, err = client.Check(ctx, &healthpb.HealthCheckRequest{})
+	require.Error(t, err)
+
+	st := status.Convert(err)
+	require.Len(t, st.Details(), 1)
+	retryInfo, ok := st.Details()[0].(*errdetails.RetryInfo)
+	require.True(t, ok)
+	assert.Equal(t, 5*time.Minute, retryInfo.RetryDelay.AsDuration())
+}
+
+func TestUnaryInterceptor_MetadataIdentity(t *testing.T) {
+	client := newHealthClient(t)
+
+	tokenCtx := metadata.AppendToOutgoingContext(context.Background(), "api-key", "abc123")
+	for i := 0; i < 3; i++ {
+		var header metadata.MD
+		_, err := client.Check(tokenCtx, &healthpb.HealthCheckRequest{}, grpc.Header(&header))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"token"}, header.Get("x-ratelimit-type"))
+	}
+
+	_, err := client.Check(tokenCtx, &healthpb.HealthCheckRequest{})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	forwardedCtx := metadata.AppendToOutgoingContext(context.Background(), "x-forwarded-for", "203.0.113.1, 10.0.0.1")
+	_, err = client.Check(forwardedCtx, &healthpb.HealthCheckRequest{})
+	assert.NoError(t, err)
+}
+
+func TestStreamInterceptor(t *testing.T) {
+	client := newHealthClient(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for i := 0; i < 2; i++ {
+		stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{})
+		require.NoError(t, err)
+		_, err = stream.Recv()
+		require.NoError(t, err)
+	}
+
+	stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+func TestUnaryInterceptor_NoIdentity(t *testing.T) {
+	client := newHealthClient(t, WithKeyExtractor(Metadata("x-tenant")))
+
+	_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	tenantCtx := metadata.AppendToOutgoingContext(context.Background(), "x-tenant", "acme")
+	_, err = client.Check(tenantCtx, &healthpb.HealthCheckRequest{})
+	assert.NoError(t, err)
+}
+
+func TestMetadataExtractor(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-tenant", "acme"))
+
+	ids := Metadata("x-tenant").Extract(ctx, "/svc/Method")
+	assert.Equal(t, []ratelimiter.Identity{{Type: ratelimiter.HeaderLimit, Value: "x-tenant=acme"}}, ids)
+	assert.Empty(t, Metadata("x-missing").Extract(ctx, "/svc/Method"))
+}
@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tiago-kimura/rate-limiter/pkg/ratelimiter"
+)
+
+// Concurrency records concurrency limiter activity, labelled by limit type.
+// Keys are not used as labels to keep the number of series bounded. A nil
+// *Concurrency records nothing.
+type Concurrency struct {
+	inFlight *prometheus.GaugeVec
+	acquired *prometheus.CounterVec
+	rejected *prometheus.CounterVec
+}
+
+func NewConcurrency(registerer prometheus.Registerer) *Concurrency {
+	c := &Concurrency{
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: "concurrency",
+			Name:      "in_flight",
+			Help:      "Requests currently holding a concurrency slot on this instance.",
+		}, []string{"limit_type"}),
+		acquired: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: "concurrency",
+			Name:      "acquired_total",
+			Help:      "Concurrency slots acquired.",
+		}, []string{"limit_type"}),
+		rejected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: "concurrency",
+			Name:      "rejected_total",
+			Help:      "Requests rejected because the concurrency limit was reached.",
+		}, []string{"limit_type"}),
+	}
+
+	registerer.MustRegister(c.inFlight, c.acquired, c.rejected)
+
+	return c
+}
+
+func (c *Concurrency) Acquired(limitType ratelimiter.LimitType) {
+	if c == nil {
+		return
+	}
+	c.acquired.WithLabelValues(string(limitType)).Inc()
+	c.inFlight.WithLabelValues(string(limitType)).Inc()
+}
+
+func (c *Concurrency) Released(limitType ratelimiter.LimitType) {
+	if c == nil {
+		return
+	}
+	c.inFlight.WithLabelValues(string(limitType)).Dec()
+}
+
+func (c *Concurrency) Rejected(limitType ratelimiter.LimitType) {
+	if c == nil {
+		return
+	}
+	c.rejected.WithLabelValues(string(limitType)).Inc()
+}
@@ -0,0 +1,33 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/tiago-kimura/rate-limiter/pkg/ratelimiter"
+)
+
+func TestConcurrency(t *testing.T) {
+	c := NewConcurrency(prometheus.NewRegistry())
+
+	c.Acquired(ratelimiter.IPLimit)
+	c.Acquired(ratelimiter.IPLimit)
+	c.Released(ratelimiter.IPLimit)
+	c.Rejected(ratelimiter.TokenLimit)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(c.inFlight.WithLabelValues("ip")))
+	assert.Equal(t, 2.0, testutil.ToFloat64(c.acquired.WithLabelValues("ip")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(c.rejected.WithLabelValues("token")))
+}
+
+func TestConcurrency_Nil(t *testing.T) {
+	var c *Concurrency
+
+	assert.NotPanics(t, func() {
+		c.Acquired(ratelimiter.IPLimit)
+		c.Released(ratelimiter.IPLimit)
+		c.Rejected(ratelimiter.IPLimit)
+	})
+}
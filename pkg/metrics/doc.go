@@ -0,0 +1,6 @@
+// Package metrics exposes Prometheus metrics for the rate limiter. Collectors
+// are registered on a caller supplied prometheus.Registerer, so they can be
+// served from a dedicated registry or the default one.
+package metrics
+
+const Namespace = "ratelimiter"
@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Storage records the results of storage health probes. A nil *Storage
+// records nothing.
+type Storage struct {
+	up       prometheus.Gauge
+	failures prometheus.Counter
+	latency  prometheus.Histogram
+}
+
+func NewStorage(registerer prometheus.Registerer) *Storage {
+	s := &Storage{
+		up: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: "storage",
+			Name:      "up",
+			Help:      "Whether the last storage health probe succeeded.",
+		}),
+		failures: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: "storage",
+			Name:      "ping_failures_total",
+			Help:      "Storage health probes that failed.",
+		}),
+		latency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Subsystem: "storage",
+			Name:      "ping_duration_seconds",
+			Help:      "Duration of storage health probes.",
+			Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 12),
+		}),
+	}
+
+	registerer.MustRegister(s.up, s.failures, s.latency)
+
+	return s
+}
+
+// ObservePing matches the storage.WithHealthObserver callback.
+func (s *Storage) ObservePing(err error, latency time.Duration) {
+	if s == nil {
+		return
+	}
+
+	s.latency.Observe(latency.Seconds())
+	if err != nil {
+		s.up.Set(0)
+		s.failures.Inc()
+		return
+	}
+	s.up.Set(1)
+}
@@ -0,0 +1,25 @@
+package metrics
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStorage(t *testing.T) {
+	s := NewStorage(prometheus.NewRegistry())
+
+	s.ObservePing(nil, time.Millisecond)
+	assert.Equal(t, 1.0, testutil.ToFloat64(s.up))
+
+	s.ObservePing(errors.New("connection refused"), time.Millisecond)
+	assert.Equal(t, 0.0, testutil.ToFloat64(s.up))
+	assert.Equal(t, 1.0, testutil.ToFloat64(s.failures))
+
+	var nilStorage *Storage
+	assert.NotPanics(t, func() { nilStorage.ObservePing(nil, time.Millisecond) })
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/tiago-kimura/rate-limiter/pkg/ratelimiter"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// auditContext attaches route and the request ID of r to ctx, for the audit
+// events of ratelimiter.WithAuditLogger. Requests without an ID get one,
+// which is passed on with the request and echoed in the response so clients
+// can quote it.
+func auditContext(ctx context.Context, w http.ResponseWriter, r *http.Request, route string) context.Context {
+	requestID := r.Header.Get(RequestIDHeader)
+	if requestID == "" {
+		requestID = newRequestID()
+		r.Header.Set(RequestIDHeader, requestID)
+		w.Header().Set(RequestIDHeader, requestID)
+	}
+
+	return ratelimiter.ContextWithAuditInfo(ctx, ratelimiter.AuditInfo{
+		Route:     route,
+		RequestID: requestID,
+	})
+}
+
+// routeOf names the route of r by its gorilla/mux path template, so requests
+// to /users/1 and /users/2 share "GET /users/{id}". Outside a mux route the
+// path is used as is.
+func routeOf(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			return r.Method + " " + template
+		}
+	}
+	return r.Method + " " + r.URL.Path
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
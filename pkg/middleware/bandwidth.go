@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/tiago-kimura/rate-limiter/pkg/ratelimiter"
+)
+
+// throttleChunk is the largest write passed through a throttledWriter at
+// once, so a large write is paced rather than sent in one burst.
+const throttleChunk = 32 * 1024
+
+// WithBandwidthLimiter counts request body and response bytes against the
+// BandwidthLimiter's budget, if it has one, and, when it sets BytesPerSecond,
+// throttles responses to that speed.
+func WithBandwidthLimiter(limiter *ratelimiter.BandwidthLimiter) Option {
+	return func(m *RateLimiterMiddleware) {
+		m.bandwidth = limiter
+	}
+}
+
+func writeBandwidthHeaders(w http.ResponseWriter, usage *ratelimiter.BandwidthUsage) {
+	w.Header().Set("X-Bandwidth-Limit", fmt.Sprintf("%d", usage.Limit))
+	w.Header().Set("X-Bandwidth-Remaining", fmt.Sprintf("%d", usage.Remaining()))
+	w.Header().Set("X-Bandwidth-Reset", fmt.Sprintf("%d", usage.ResetTime.Unix()))
+}
+
+func writeBandwidthExceeded(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusTooManyRequests)
+
+	response := ErrorResponse{
+		Message: "you have transferred the maximum number of bytes allowed within a certain time frame",
+		Error:   "bandwidth_limit_exceeded",
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+type countingReader struct {
+	io.ReadCloser
+	bytes int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.bytes += int64(n)
+	return n, err
+}
+
+// throttledWriter paces writes so that the response, on average since its
+// first byte, is not sent faster than rate bytes per second.
+type throttledWriter struct {
+	http.ResponseWriter
+	ctx     context.Context
+	rate    int64
+	start   time.Time
+	written int64
+	waited  time.Duration
+}
+
+func (w *throttledWriter) Write(b []byte) (int, error) {
+	total := 0
+	for len(b) > 0 {
+		chunk := b
+		if len(chunk) > throttleChunk {
+			chunk = chunk[:throttleChunk]
+		}
+
+		if err := w.wait(); err != nil {
+			return total, err
+		}
+
+		n, err := w.ResponseWriter.Write(chunk)
+		total += n
+		w.written += int64(n)
+		if err != nil {
+			return total, err
+		}
+		b = b[n:]
+	}
+	return total, nil
+}
+
+func (w *throttledWriter) wait() error {
+	due := w.start.Add(time.Duration(float64(w.written) / float64(w.rate) * float64(time.Second)))
+	delay := time.Until(due)
+	if delay <= 0 {
+		return nil
+	}
+
+	w.waited += delay
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-w.ctx.Done():
+		return w.ctx.Err()
+	}
+}
+
+func (w *throttledWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/tiago-kimura/rate-limiter/pkg/metrics"
+	"github.com/tiago-kimura/rate-limiter/pkg/ratelimiter"
+)
+
+// ConcurrencyMiddleware holds a concurrency slot for the duration of each
+// request and rejects requests once the identity has too many in flight.
+type ConcurrencyMiddleware struct {
+	limiter      *ratelimiter.ConcurrencyLimiter
+	keyExtractor KeyExtractor
+	timeout      time.Duration
+	metrics      *metrics.Concurrency
+}
+
+type ConcurrencyOption func(*ConcurrencyMiddleware)
+
+func WithConcurrencyKeyExtractor(extractor KeyExtractor) ConcurrencyOption {
+	return func(m *ConcurrencyMiddleware) {
+		m.keyExtractor = extractor
+	}
+}
+
+// WithConcurrencyTimeout bounds the storage calls that acquire and release a
+// slot, not the request itself.
+func WithConcurrencyTimeout(timeout time.Duration) ConcurrencyOption {
+	return func(m *ConcurrencyMiddleware) {
+		m.timeout = timeout
+	}
+}
+
+func WithConcurrencyMetrics(metrics *metrics.Concurrency) ConcurrencyOption {
+	return func(m *ConcurrencyMiddleware) {
+		m.metrics = metrics
+	}
+}
+
+func NewConcurrencyMiddleware(limiter *ratelimiter.ConcurrencyLimiter, opts ...ConcurrencyOption) *ConcurrencyMiddleware {
+	m := &ConcurrencyMiddleware{
+		limiter:      limiter,
+		keyExtractor: DefaultKeyExtractor,
+		timeout:      DefaultTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m
+}
+
+func (m *ConcurrencyMiddleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), m.timeout)
+		lease, err := m.limiter.Acquire(ctx, m.keyExtractor.Extract(r)...)
+		cancel()
+		if err != nil {
+			writeCheckError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("X-Concurrency-Limit", fmt.Sprintf("%d", lease.Limit))
+		w.Header().Set("X-Concurrency-Remaining", fmt.Sprintf("%d", lease.Remaining()))
+
+		if !lease.Allowed {
+			m.metrics.Rejected(lease.LimitType)
+			writeConcurrencyExceeded(w)
+			return
+		}
+
+		m.metrics.Acquired(lease.LimitType)
+		stopRenewing := m.keepAlive(r.Context(), lease)
+		defer func() {
+			stopRenewing()
+			m.metrics.Released(lease.LimitType)
+
+			// The request context may already be canceled once the client is
+			// gone, and the slot must still be given back.
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), m.timeout)
+			defer cancel()
+			if err := lease.Release(ctx); err != nil {
+				slog.ErrorContext(ctx, "failed to release concurrency slot", "error", err)
+			}
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// keepAlive renews the lease a few times per TTL until the returned function
+// is called, so a request running longer than the TTL keeps its slot.
+func (m *ConcurrencyMiddleware) keepAlive(ctx context.Context, lease *ratelimiter.Lease) func() {
+	// The slot is held until the handler returns, even if the client is gone.
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(lease.TTL / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			renewCtx, cancelRenew := context.WithTimeout(ctx, m.timeout)
+			renewed, err := lease.Renew(renewCtx)
+			cancelRenew()
+			if err != nil {
+				slog.WarnContext(ctx, "failed to renew concurrency slot", "error", err)
+				continue
+			}
+			if !renewed {
+				slog.WarnContext(ctx, "concurrency slot expired while the request was running")
+				return
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+func writeConcurrencyExceeded(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusTooManyRequests)
+
+	response := ErrorResponse{
+		Message: "you have reached the maximum number of requests allowed in flight at the same time",
+		Error:   "concurrency_limit_exceeded",
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+var (
+	originalURIHeaders    = []string{"X-Original-URI", "X-Forwarded-Uri"}
+	originalMethodHeaders = []string{"X-Original-Method", "X-Forwarded-Method"}
+)
+
+// DecisionHandler answers forward-auth subrequests from a proxy such as
+// nginx (auth_request) or Traefik (forwardAuth). The original method and URI
+// are read from X-Original-Method/X-Original-URI or
+// X-Forwarded-Method/X-Forwarded-Uri, and the request is checked as if it
+// were the original one. Allowed requests get 200 and denied ones
+// deniedStatus, both with the rate limit headers. Requests without an
+// identity to limit are denied too.
+func (m *RateLimiterMiddleware) DecisionHandler(deniedStatus int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		original := originalRequest(r)
+
+		// The mux route is the one of the decision endpoint, not of the
+		// original request.
+		route := original.Method + " " + original.URL.Path
+		ctx, cancel := context.WithTimeout(auditContext(r.Context(), w, original, route), m.timeout)
+		defer cancel()
+
+		result, err := m.rateLimiter.Check(ctx, m.keyExtractor.Extract(original)...)
+		if err != nil {
+			writeCheckError(w, err, deniedStatus)
+			return
+		}
+
+		writeRateLimitHeaders(w, result)
+
+		if !result.Allowed {
+			writeRateLimitExceeded(w, deniedStatus)
+			return
+		}
+
+		if !m.admit(ctx, w, result, deniedStatus) {
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func originalRequest(r *http.Request) *http.Request {
+	original := r.Clone(r.Context())
+
+	if method := firstHeader(r, originalMethodHeaders); method != "" {
+		original.Method = method
+	}
+
+	if uri := firstHeader(r, originalURIHeaders); uri != "" {
+		if u, err := url.ParseRequestURI(uri); err == nil {
+			original.URL = u
+			original.RequestURI = uri
+		}
+	}
+
+	return original
+}
+
+func firstHeader(r *http.Request, names []string) string {
+	for _, name := range names {
+		if value := r.Header.Get(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,8 @@
+// Package middleware exposes a ratelimiter.RateLimiter as net/http middleware.
+//
+// The identity of a request is resolved by a KeyExtractor. The default one
+// limits by client IP and by the token in the API_KEY header:
+//
+//	mw := middleware.NewRateLimiterMiddleware(rl)
+//	router.Use(mw.Handler)
+package middleware
@@ -0,0 +1,200 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/tiago-kimura/rate-limiter/pkg/ratelimiter"
+)
+
+// KeyExtractor resolves the identities a request can be limited by, in order
+// of precedence. The rate limiter applies the first one that has a limit.
+type KeyExtractor interface {
+	Extract(r *http.Request) []ratelimiter.Identity
+}
+
+type KeyExtractorFunc func(r *http.Request) []ratelimiter.Identity
+
+func (f KeyExtractorFunc) Extract(r *http.Request) []ratelimiter.Identity {
+	return f(r)
+}
+
+var DefaultKeyExtractor = FirstOf(TokenHeader(DefaultAPIKeyHeader), IP())
+
+func IP() KeyExtractor {
+	return KeyExtractorFunc(func(r *http.Request) []ratelimiter.Identity {
+		return single(ratelimiter.IPLimit, ClientIP(r))
+	})
+}
+
+// TrustedIP is IP reading X-Forwarded-For and X-Real-IP only from the
+// given proxies, see TrustedClientIP.
+func TrustedIP(trusted ...netip.Prefix) KeyExtractor {
+	return KeyExtractorFunc(func(r *http.Request) []ratelimiter.Identity {
+		return single(ratelimiter.IPLimit, TrustedClientIP(r, trusted...))
+	})
+}
+
+func TokenHeader(name string) KeyExtractor {
+	return KeyExtractorFunc(func(r *http.Request) []ratelimiter.Identity {
+		return single(ratelimiter.TokenLimit, r.Header.Get(name))
+	})
+}
+
+func Header(name string) KeyExtractor {
+	name = http.CanonicalHeaderKey(name)
+	return KeyExtractorFunc(func(r *http.Request) []ratelimiter.Identity {
+		return named(ratelimiter.HeaderLimit, name, r.Header.Get(name))
+	})
+}
+
+func Cookie(name string) KeyExtractor {
+	return KeyExtractorFunc(func(r *http.Request) []ratelimiter.Identity {
+		cookie, err := r.Cookie(name)
+		if err != nil {
+			return nil
+		}
+		return named(ratelimiter.CookieLimit, name, cookie.Value)
+	})
+}
+
+func Query(name string) KeyExtractor {
+	return KeyExtractorFunc(func(r *http.Request) []ratelimiter.Identity {
+		return named(ratelimiter.QueryLimit, name, r.URL.Query().Get(name))
+	})
+}
+
+// PathVar extracts a gorilla/mux route variable, so the middleware must run
+// after route matching (router.Use does).
+func PathVar(name string) KeyExtractor {
+	return KeyExtractorFunc(func(r *http.Request) []ratelimiter.Identity {
+		return named(ratelimiter.PathLimit, name, mux.Vars(r)[name])
+	})
+}
+
+// ClientCertSubject extracts the subject of the TLS client certificate.
+func ClientCertSubject() KeyExtractor {
+	return KeyExtractorFunc(func(r *http.Request) []ratelimiter.Identity {
+		if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+			return nil
+		}
+		return single(ratelimiter.ClientCertLimit, r.TLS.PeerCertificates[0].Subject.String())
+	})
+}
+
+// FirstOf returns the identities of all extractors in order, so the first
+// one with a limit wins.
+func FirstOf(extractors ...KeyExtractor) KeyExtractor {
+	return KeyExtractorFunc(func(r *http.Request) []ratelimiter.Identity {
+		var ids []ratelimiter.Identity
+		for _, extractor := range extractors {
+			ids = append(ids, extractor.Extract(r)...)
+		}
+		return ids
+	})
+}
+
+// Combine limits by all extractors together. It yields nothing unless every
+// extractor yields an identity.
+func Combine(extractors ...KeyExtractor) KeyExtractor {
+	return KeyExtractorFunc(func(r *http.Request) []ratelimiter.Identity {
+		parts := make([]ratelimiter.Identity, 0, len(extractors))
+		for _, extractor := range extractors {
+			ids := extractor.Extract(r)
+			if len(ids) == 0 {
+				return nil
+			}
+			parts = append(parts, ids[0])
+		}
+		return []ratelimiter.Identity{ratelimiter.CombineIdentities(parts...)}
+	})
+}
+
+func ClientIP(r *http.Request) string {
+	forwarded := r.Header.Get("X-Forwarded-For")
+	if forwarded != "" {
+		ips := strings.Split(forwarded, ",")
+		if len(ips) > 0 {
+			return strings.TrimSpace(ips[0])
+		}
+	}
+
+	realIP := r.Header.Get("X-Real-IP")
+	if realIP != "" {
+		return realIP
+	}
+
+	return peerIP(r)
+}
+
+// TrustedClientIP returns the peer address unless it is one of the trusted
+// proxies. Otherwise it returns the last X-Forwarded-For entry that is not a
+// trusted proxy, since entries before it may be set by the client, falling
+// back to X-Real-IP.
+func TrustedClientIP(r *http.Request, trusted ...netip.Prefix) string {
+	peer := peerIP(r)
+	if !isTrusted(peer, trusted) {
+		return peer
+	}
+
+	if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+		ips := strings.Split(strings.Join(forwarded, ","), ",")
+		for i := len(ips) - 1; i >= 0; i-- {
+			ip := strings.TrimSpace(ips[i])
+			if ip != "" && (i == 0 || !isTrusted(ip, trusted)) {
+				return ip
+			}
+		}
+	}
+
+	if realIP := r.Header.Get("X-Real-IP"); realIP != "" {
+		return realIP
+	}
+
+	return peer
+}
+
+// FromTrustedProxy reports whether the peer of r is one of the trusted
+// proxies.
+func FromTrustedProxy(r *http.Request, trusted ...netip.Prefix) bool {
+	return isTrusted(peerIP(r), trusted)
+}
+
+func peerIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
+
+func isTrusted(ip string, trusted []netip.Prefix) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func single(limitType ratelimiter.LimitType, value string) []ratelimiter.Identity {
+	if value == "" {
+		return nil
+	}
+	return []ratelimiter.Identity{{Type: limitType, Value: value}}
+}
+
+func named(limitType ratelimiter.LimitType, name, value string) []ratelimiter.Identity {
+	if value == "" {
+		return nil
+	}
+	return single(limitType, name+"="+value)
+}
@@ -0,0 +1,177 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/tiago-kimura/rate-limiter/pkg/clock"
+	"github.com/tiago-kimura/rate-limiter/pkg/ratelimiter"
+)
+
+const (
+	DefaultJWTHeader   = "Authorization"
+	DefaultJWTKeyClaim = "sub"
+)
+
+var jwtAlgorithms = []jose.SignatureAlgorithm{
+	jose.HS256, jose.HS384, jose.HS512,
+	jose.RS256, jose.RS384, jose.RS512,
+	jose.PS256, jose.PS384, jose.PS512,
+	jose.ES256, jose.ES384, jose.ES512,
+}
+
+// JWKS is a set of JSON Web Keys used to verify JWT signatures. It supports
+// symmetric ("oct") keys for HMAC and RSA/ECDSA public keys.
+type JWKS struct {
+	set jose.JSONWebKeySet
+}
+
+func ParseJWKS(data []byte) (*JWKS, error) {
+	var set jose.JSONWebKeySet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS: %w", err)
+	}
+
+	if len(set.Keys) == 0 {
+		return nil, fmt.Errorf("JWKS has no keys")
+	}
+
+	return &JWKS{set: set}, nil
+}
+
+func LoadJWKSFile(path string) (*JWKS, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWKS file: %w", err)
+	}
+
+	return ParseJWKS(data)
+}
+
+func (k *JWKS) keysFor(kid string) []jose.JSONWebKey {
+	if kid == "" {
+		return k.set.Keys
+	}
+	return k.set.Key(kid)
+}
+
+type JWTConfig struct {
+	Keys *JWKS
+	// Header carries the token as "Bearer <jwt>". Defaults to Authorization.
+	Header string
+	// KeyClaim is the claim the rate limit key is derived from, e.g. sub,
+	// client_id or tenant. Defaults to sub.
+	KeyClaim string
+	// TierClaim is the claim selecting the limit tier. Optional.
+	TierClaim string
+	Issuer    string
+	Audience  string
+	// Clock checks expiry and not-before times. Defaults to the system clock.
+	Clock clock.Clock
+}
+
+// JWT extracts the identity from a verified bearer JWT. Missing, invalid or
+// expired tokens yield no identity, so it is meant to be combined with a
+// fallback such as FirstOf(JWT(config), IP()).
+func JWT(config JWTConfig) KeyExtractor {
+	if config.Header == "" {
+		config.Header = DefaultJWTHeader
+	}
+	if config.KeyClaim == "" {
+		config.KeyClaim = DefaultJWTKeyClaim
+	}
+	if config.Clock == nil {
+		config.Clock = clock.Real
+	}
+
+	return KeyExtractorFunc(func(r *http.Request) []ratelimiter.Identity {
+		raw, ok := bearerToken(r.Header.Get(config.Header))
+		if !ok {
+			return nil
+		}
+
+		claims, err := verifyJWT(raw, config)
+		if err != nil {
+			return nil
+		}
+
+		value := claimString(claims, config.KeyClaim)
+		if value == "" {
+			return nil
+		}
+
+		return []ratelimiter.Identity{{
+			Type:  ratelimiter.JWTLimit,
+			Value: config.KeyClaim + "=" + value,
+			Tier:  claimString(claims, config.TierClaim),
+		}}
+	})
+}
+
+func verifyJWT(raw string, config JWTConfig) (map[string]interface{}, error) {
+	if config.Keys == nil {
+		return nil, fmt.Errorf("no JWT keys configured")
+	}
+
+	token, err := jwt.ParseSigned(raw, jwtAlgorithms)
+	if err != nil {
+		return nil, err
+	}
+
+	kid := ""
+	if len(token.Headers) > 0 {
+		kid = token.Headers[0].KeyID
+	}
+
+	for _, key := range config.Keys.keysFor(kid) {
+		var registered jwt.Claims
+		var claims map[string]interface{}
+		if err := token.Claims(key.Key, &registered, &claims); err != nil {
+			continue
+		}
+
+		expected := jwt.Expected{Issuer: config.Issuer, Time: config.Clock.Now()}
+		if config.Audience != "" {
+			expected.AnyAudience = jwt.Audience{config.Audience}
+		}
+
+		if err := registered.ValidateWithLeeway(expected, 0); err != nil {
+			return nil, err
+		}
+
+		return claims, nil
+	}
+
+	return nil, fmt.Errorf("no key verifies the JWT signature")
+}
+
+func bearerToken(header string) (string, bool) {
+	const prefix = "bearer "
+	if len(header) <= len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return "", false
+	}
+	return strings.TrimSpace(header[len(prefix):]), true
+}
+
+func claimString(claims map[string]interface{}, name string) string {
+	if name == "" {
+		return ""
+	}
+
+	switch value := claims[name].(type) {
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(value)
+	}
+}
@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/tiago-kimura/rate-limiter/pkg/ratelimiter"
+)
+
+// WithCountedStatuses makes only responses with one of the given statuses
+// count toward the limit, e.g. 401 and 403 on a login endpoint to limit
+// failed attempts only. The cost of other responses is refunded once the
+// wrapped handler returns, so X-RateLimit-Remaining reflects the count
+// before the refund. Since requests are counted until they complete, a burst
+// of more than Limit concurrent requests is denied, and can trigger a
+// BlockTime block, even if all of them would have been refunded. It has no
+// effect on the DecisionHandler, which never sees the response.
+func WithCountedStatuses(statuses ...int) Option {
+	return func(m *RateLimiterMiddleware) {
+		m.countedStatuses = make(map[int]bool, len(statuses))
+		for _, status := range statuses {
+			m.countedStatuses[status] = true
+		}
+	}
+}
+
+// serveObserved runs the wrapped handler, feeding its latency and status to
+// the AdaptiveLimiter, refunding responses that should not count and
+// recording the bytes transferred against the bandwidth budget.
+func (m *RateLimiterMiddleware) serveObserved(next http.Handler, w http.ResponseWriter, r *http.Request, result *ratelimiter.CheckResult, usage *ratelimiter.BandwidthUsage) {
+	var body *countingReader
+	var throttled *throttledWriter
+	if usage != nil {
+		if rate := m.bandwidth.BytesPerSecond(); rate > 0 {
+			throttled = &throttledWriter{ResponseWriter: w, ctx: r.Context(), rate: rate, start: time.Now()}
+			w = throttled
+		}
+
+		if r.Body != nil && r.Body != http.NoBody {
+			body = &countingReader{ReadCloser: r.Body}
+			r = r.WithContext(r.Context())
+			r.Body = body
+		}
+	}
+
+	recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	start := time.Now()
+
+	next.ServeHTTP(recorder, r)
+
+	if usage != nil {
+		transferred := recorder.bytes
+		if body != nil {
+			transferred += body.bytes
+		}
+
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), m.timeout)
+		defer cancel()
+		if err := m.bandwidth.Record(ctx, usage, transferred); err != nil {
+			slog.ErrorContext(ctx, "failed to record bandwidth usage", "error", err)
+		}
+	}
+
+	if adaptive := m.rateLimiter.Adaptive(); adaptive != nil {
+		latency := time.Since(start)
+		if throttled != nil {
+			// Throttling is not the backend being slow.
+			latency -= throttled.waited
+		}
+		adaptive.Observe(latency, recorder.status >= http.StatusInternalServerError)
+	}
+
+	if m.countedStatuses != nil && !m.countedStatuses[recorder.status] {
+		// The request context may be canceled by now, the refund must still
+		// happen.
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), m.timeout)
+		defer cancel()
+		if err := m.rateLimiter.Refund(ctx, result); err != nil {
+			slog.ErrorContext(ctx, "failed to refund rate limit", "error", err)
+		}
+	}
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	bytes       int64
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += int64(n)
+	return n, err
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to
+// flush streamed proxy responses.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,150 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/tiago-kimura/rate-limiter/pkg/ratelimiter"
+)
+
+const (
+	DefaultAPIKeyHeader = "API_KEY"
+	DefaultTimeout      = 5 * time.Second
+)
+
+type RateLimiterMiddleware struct {
+	rateLimiter  *ratelimiter.RateLimiter
+	keyExtractor KeyExtractor
+	timeout      time.Duration
+	shedder      *ratelimiter.Shedder
+	bandwidth    *ratelimiter.BandwidthLimiter
+
+	countedStatuses map[int]bool
+}
+
+type Option func(*RateLimiterMiddleware)
+
+func WithKeyExtractor(extractor KeyExtractor) Option {
+	return func(m *RateLimiterMiddleware) {
+		m.keyExtractor = extractor
+	}
+}
+
+func WithTimeout(timeout time.Duration) Option {
+	return func(m *RateLimiterMiddleware) {
+		m.timeout = timeout
+	}
+}
+
+func NewRateLimiterMiddleware(rateLimiter *ratelimiter.RateLimiter, opts ...Option) *RateLimiterMiddleware {
+	m := &RateLimiterMiddleware{
+		rateLimiter:  rateLimiter,
+		keyExtractor: DefaultKeyExtractor,
+		timeout:      DefaultTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m
+}
+
+type ErrorResponse struct {
+	Message string `json:"message"`
+	Error   string `json:"error"`
+}
+
+func (m *RateLimiterMiddleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(auditContext(r.Context(), w, r, routeOf(r)), m.timeout)
+		defer cancel()
+
+		ids := m.keyExtractor.Extract(r)
+
+		var usage *ratelimiter.BandwidthUsage
+		if m.bandwidth != nil {
+			var err error
+			usage, err = m.bandwidth.Check(ctx, ids...)
+			if err != nil {
+				writeCheckError(w, err, http.StatusBadRequest)
+				return
+			}
+
+			if m.bandwidth.Budgeted() {
+				writeBandwidthHeaders(w, usage)
+			}
+
+			if !usage.Allowed {
+				writeBandwidthExceeded(w)
+				return
+			}
+		}
+
+		result, err := m.rateLimiter.Check(ctx, ids...)
+		if err != nil {
+			writeCheckError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		writeRateLimitHeaders(w, result)
+
+		if !result.Allowed {
+			writeRateLimitExceeded(w, http.StatusTooManyRequests)
+			return
+		}
+
+		if !m.admit(ctx, w, result, http.StatusServiceUnavailable) {
+			return
+		}
+
+		if m.rateLimiter.Adaptive() != nil || m.countedStatuses != nil || usage != nil {
+			m.serveObserved(next, w, r, result, usage)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeRateLimitHeaders(w http.ResponseWriter, result *ratelimiter.CheckResult) {
+	w.Header().Set("X-RateLimit-Limit", fmt.Sprintf("%d", result.Limit))
+	w.Header().Set("X-RateLimit-Remaining", fmt.Sprintf("%d", result.Remaining))
+	w.Header().Set("X-RateLimit-Reset", fmt.Sprintf("%d", result.ResetTime.Unix()))
+	w.Header().Set("X-RateLimit-Type", string(result.LimitType))
+}
+
+func writeRateLimitExceeded(w http.ResponseWriter, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	response := ErrorResponse{
+		Message: "you have reached the maximum number of requests or actions allowed within a certain time frame",
+		Error:   "rate_limit_exceeded",
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// writeCheckError answers a failed check. A request the key extractor found
+// no identity in is the client's fault and gets missingIdentityStatus.
+func writeCheckError(w http.ResponseWriter, err error, missingIdentityStatus int) {
+	if !errors.Is(err, ratelimiter.ErrNoIdentity) {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(missingIdentityStatus)
+
+	response := ErrorResponse{
+		Message: "the request carries no identity to apply a rate limit to",
+		Error:   "missing_identity",
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
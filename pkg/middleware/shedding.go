@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/tiago-kimura/rate-limiter/pkg/ratelimiter"
+)
+
+// WithShedder sheds requests that are within their own limit when the global
+// capacity left is reserved for higher priorities. Shed requests get 503, or
+// the denied status on the decision endpoint, and do not count toward their
+// own limit.
+func WithShedder(shedder *ratelimiter.Shedder) Option {
+	return func(m *RateLimiterMiddleware) {
+		m.shedder = shedder
+	}
+}
+
+// admit reports whether the request may proceed, writing the response
+// itself when it may not.
+func (m *RateLimiterMiddleware) admit(ctx context.Context, w http.ResponseWriter, result *ratelimiter.CheckResult, shedStatus int) bool {
+	if m.shedder == nil {
+		return true
+	}
+
+	admitted, err := m.shedder.Admit(ctx, result.Priority)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return false
+	}
+
+	if !admitted {
+		// The overload is not the client's doing, so retrying must not use
+		// up its quota and get it blocked.
+		if err := m.rateLimiter.Refund(ctx, result); err != nil {
+			slog.ErrorContext(ctx, "failed to refund shed request", "error", err)
+		}
+		writeRateLimitHeaders(w, result)
+		writeLoadShed(w, shedStatus)
+		return false
+	}
+
+	return true
+}
+
+func writeLoadShed(w http.ResponseWriter, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	response := ErrorResponse{
+		Message: "the service is under heavy load and is only serving higher priority requests, try again later",
+		Error:   "load_shed",
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
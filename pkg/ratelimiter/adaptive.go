@@ -0,0 +1,182 @@
+package ratelimiter
+
+import (
+	"sync"
+	"time"
+
+	"github.com/tiago-kimura/rate-limiter/pkg/clock"
+)
+
+// AdaptiveConfig tunes an AdaptiveLimiter. A zero LatencyTarget or
+// ErrorRateThreshold disables that signal; the other zero fields take the
+// defaults below.
+type AdaptiveConfig struct {
+	// LatencyTarget is the average backend latency above which the backend is
+	// considered overloaded.
+	LatencyTarget time.Duration
+	// ErrorRateThreshold is the fraction of 5xx responses above which the
+	// backend is considered overloaded.
+	ErrorRateThreshold float64
+
+	// Interval is how often the limits are adjusted, from the responses
+	// observed since the last adjustment.
+	Interval time.Duration
+	// MinSamples is the number of responses an interval needs to count as
+	// evidence of overload. Intervals with fewer responses let the limits
+	// grow back.
+	MinSamples int
+
+	// Decrease multiplies the limits when the backend is overloaded.
+	Decrease float64
+	// Increase is added back to the limit factor when the backend is healthy.
+	Increase float64
+	// MinFactor is the lowest fraction of the configured limits applied.
+	MinFactor float64
+}
+
+const (
+	DefaultAdaptiveInterval   = time.Second
+	DefaultAdaptiveMinSamples = 10
+	DefaultAdaptiveDecrease   = 0.7
+	DefaultAdaptiveIncrease   = 0.05
+	DefaultAdaptiveMinFactor  = 0.1
+)
+
+// AdaptiveLimiter scales every limit of a RateLimiter by a factor between
+// MinFactor and 1, adjusted with AIMD from the latency and errors of the
+// responses passed to Observe: the factor is cut multiplicatively while the
+// backend is overloaded and grows back additively once it is healthy.
+//
+// The factor is local to the process, so every instance reacts to the
+// backend as it observes it.
+type AdaptiveLimiter struct {
+	config AdaptiveConfig
+	clock  clock.Clock
+
+	mu          sync.Mutex
+	factor      float64
+	windowStart time.Time
+	samples     int
+	failures    int
+	latency     time.Duration
+}
+
+type AdaptiveOption func(*AdaptiveLimiter)
+
+// WithAdaptiveClock times the adjustment intervals by the given clock instead
+// of the system clock.
+func WithAdaptiveClock(c clock.Clock) AdaptiveOption {
+	return func(a *AdaptiveLimiter) {
+		a.clock = c
+	}
+}
+
+func NewAdaptiveLimiter(config AdaptiveConfig, opts ...AdaptiveOption) *AdaptiveLimiter {
+	if config.Interval <= 0 {
+		config.Interval = DefaultAdaptiveInterval
+	}
+	if config.MinSamples <= 0 {
+		config.MinSamples = DefaultAdaptiveMinSamples
+	}
+	if config.Decrease <= 0 || config.Decrease >= 1 {
+		config.Decrease = DefaultAdaptiveDecrease
+	}
+	if config.Increase <= 0 {
+		config.Increase = DefaultAdaptiveIncrease
+	}
+	if config.MinFactor <= 0 || config.MinFactor > 1 {
+		config.MinFactor = DefaultAdaptiveMinFactor
+	}
+
+	a := &AdaptiveLimiter{
+		config: config,
+		clock:  clock.Real,
+		factor: 1,
+	}
+
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	a.windowStart = a.clock.Now()
+	return a
+}
+
+// WithAdaptiveLimiter scales all limits of the RateLimiter by the factor of
+// the AdaptiveLimiter.
+func WithAdaptiveLimiter(adaptive *AdaptiveLimiter) Option {
+	return func(rl *RateLimiter) {
+		rl.adaptive = adaptive
+	}
+}
+
+// Adaptive returns the AdaptiveLimiter set with WithAdaptiveLimiter, or nil.
+func (rl *RateLimiter) Adaptive() *AdaptiveLimiter {
+	return rl.adaptive
+}
+
+// Observe records a backend response. failed should be true for 5xx
+// responses.
+func (a *AdaptiveLimiter) Observe(latency time.Duration, failed bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.samples++
+	a.latency += latency
+	if failed {
+		a.failures++
+	}
+
+	a.adjust(a.clock.Now())
+}
+
+// adjust moves the factor once an interval is over. Too few responses are
+// no evidence of overload, e.g. when traffic is low or the reduced limits
+// reject most of it, so the factor grows back for every interval elapsed.
+func (a *AdaptiveLimiter) adjust(now time.Time) {
+	elapsed := now.Sub(a.windowStart)
+	if elapsed < a.config.Interval {
+		return
+	}
+
+	if a.samples >= a.config.MinSamples && a.overloaded() {
+		a.factor *= a.config.Decrease
+		if a.factor < a.config.MinFactor {
+			a.factor = a.config.MinFactor
+		}
+	} else {
+		a.factor += a.config.Increase * float64(elapsed/a.config.Interval)
+		if a.factor > 1 {
+			a.factor = 1
+		}
+	}
+
+	a.windowStart = now
+	a.samples = 0
+	a.failures = 0
+	a.latency = 0
+}
+
+func (a *AdaptiveLimiter) overloaded() bool {
+	if a.config.LatencyTarget > 0 && a.latency/time.Duration(a.samples) > a.config.LatencyTarget {
+		return true
+	}
+	return a.config.ErrorRateThreshold > 0 && float64(a.failures)/float64(a.samples) > a.config.ErrorRateThreshold
+}
+
+// Factor returns the fraction of the configured limits currently applied.
+func (a *AdaptiveLimiter) Factor() float64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.adjust(a.clock.Now())
+	return a.factor
+}
+
+func (a *AdaptiveLimiter) scale(limit int64) int64 {
+	scaled := int64(float64(limit) * a.Factor())
+	if scaled < 1 {
+		return 1
+	}
+	return scaled
+}
@@ -0,0 +1,152 @@
+package ratelimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tiago-kimura/rate-limiter/pkg/clock"
+	"github.com/tiago-kimura/rate-limiter/pkg/storage"
+)
+
+func newTestAdaptiveLimiter(config AdaptiveConfig) (*AdaptiveLimiter, *clock.Fake) {
+	fake := clock.NewFake(time.Now())
+	return NewAdaptiveLimiter(config, WithAdaptiveClock(fake)), fake
+}
+
+// observeInterval records samples responses and then moves to the next
+// interval so the limiter adjusts.
+func observeInterval(adaptive *AdaptiveLimiter, fake *clock.Fake, samples int, latency time.Duration, failed bool) {
+	for i := 0; i < samples-1; i++ {
+		adaptive.Observe(latency, failed)
+	}
+	fake.Advance(adaptive.config.Interval)
+	adaptive.Observe(latency, failed)
+}
+
+func TestAdaptiveLimiter_ErrorRate(t *testing.T) {
+	adaptive, fake := newTestAdaptiveLimiter(AdaptiveConfig{
+		ErrorRateThreshold: 0.1,
+		MinSamples:         5,
+		Decrease:           0.5,
+		Increase:           0.25,
+	})
+	assert.Equal(t, 1.0, adaptive.Factor())
+
+	observeInterval(adaptive, fake, 10, time.Millisecond, true)
+	assert.Equal(t, 0.5, adaptive.Factor())
+
+	observeInterval(adaptive, fake, 10, time.Millisecond, true)
+	assert.Equal(t, 0.25, adaptive.Factor())
+
+	observeInterval(adaptive, fake, 10, time.Millisecond, false)
+	assert.Equal(t, 0.5, adaptive.Factor())
+
+	for i := 0; i < 5; i++ {
+		observeInterval(adaptive, fake, 10, time.Millisecond, false)
+	}
+	assert.Equal(t, 1.0, adaptive.Factor())
+}
+
+func TestAdaptiveLimiter_LatencyAndMinFactor(t *testing.T) {
+	adaptive, fake := newTestAdaptiveLimiter(AdaptiveConfig{
+		LatencyTarget: 100 * time.Millisecond,
+		MinSamples:    5,
+		Decrease:      0.5,
+		MinFactor:     0.2,
+	})
+
+	observeInterval(adaptive, fake, 10, 50*time.Millisecond, false)
+	assert.Equal(t, 1.0, adaptive.Factor())
+
+	for i := 0; i < 5; i++ {
+		observeInterval(adaptive, fake, 10, 200*time.Millisecond, false)
+	}
+	assert.Equal(t, 0.2, adaptive.Factor())
+}
+
+func TestAdaptiveLimiter_MinSamples(t *testing.T) {
+	adaptive, fake := newTestAdaptiveLimiter(AdaptiveConfig{ErrorRateThreshold: 0.1, MinSamples: 5})
+
+	observeInterval(adaptive, fake, 4, time.Millisecond, true)
+	assert.Equal(t, 1.0, adaptive.Factor())
+}
+
+func TestAdaptiveLimiter_SparseIntervalsGrowBack(t *testing.T) {
+	adaptive, fake := newTestAdaptiveLimiter(AdaptiveConfig{ErrorRateThreshold: 0.1, MinSamples: 5, Decrease: 0.5, Increase: 0.1})
+
+	observeInterval(adaptive, fake, 10, time.Millisecond, true)
+	assert.Equal(t, 0.5, adaptive.Factor())
+
+	// A few failures are not enough evidence to cut the limits further.
+	observeInterval(adaptive, fake, 2, time.Millisecond, true)
+	assert.InDelta(t, 0.6, adaptive.Factor(), 1e-9)
+
+	// Without any traffic the factor grows back once per interval.
+	fake.Advance(3 * adaptive.config.Interval)
+	assert.InDelta(t, 0.9, adaptive.Factor(), 1e-9)
+
+	fake.Advance(10 * adaptive.config.Interval)
+	assert.Equal(t, 1.0, adaptive.Factor())
+}
+
+func TestRateLimiter_AdaptiveLimit(t *testing.T) {
+	adaptive, fake := newTestAdaptiveLimiter(AdaptiveConfig{ErrorRateThreshold: 0.1, MinSamples: 1, Decrease: 0.5})
+	rateLimiter := NewRateLimiter(storage.NewMockStorage(),
+		Config{Limit: 4, Window: time.Minute, BlockTime: time.Minute},
+		WithAdaptiveLimiter(adaptive),
+	)
+	ctx := context.Background()
+
+	observeInterval(adaptive, fake, 1, time.Millisecond, true)
+
+	for i := 0; i < 2; i++ {
+		result, err := rateLimiter.CheckLimit(ctx, "192.168.1.1", "")
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, int64(2), result.Limit)
+	}
+
+	result, err := rateLimiter.CheckLimit(ctx, "192.168.1.1", "")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+}
+
+func TestRateLimiter_AdaptiveLimitDoesNotBlock(t *testing.T) {
+	adaptive, fake := newTestAdaptiveLimiter(AdaptiveConfig{ErrorRateThreshold: 0.1, MinSamples: 1, Decrease: 0.5, Increase: 0.5})
+	rateLimiter := NewRateLimiter(storage.NewMockStorage(),
+		Config{Limit: 4, Window: time.Minute, BlockTime: time.Hour},
+		WithAdaptiveLimiter(adaptive),
+	)
+	ctx := context.Background()
+
+	check := func() *CheckResult {
+		result, err := rateLimiter.CheckLimit(ctx, "192.168.1.1", "")
+		require.NoError(t, err)
+		return result
+	}
+
+	observeInterval(adaptive, fake, 1, time.Millisecond, true)
+
+	assert.True(t, check().Allowed)
+	assert.True(t, check().Allowed)
+	for i := 0; i < 3; i++ {
+		result := check()
+		assert.False(t, result.Allowed, "over the reduced limit")
+		assert.True(t, result.ResetTime.Before(time.Now().Add(time.Minute+time.Second)), "not blocked")
+	}
+
+	// Once the backend recovers, the rejected requests have not used up the
+	// configured limit and no block is left behind.
+	fake.Advance(adaptive.config.Interval)
+	assert.Equal(t, 1.0, adaptive.Factor())
+
+	assert.True(t, check().Allowed)
+	assert.True(t, check().Allowed)
+
+	result := check()
+	assert.False(t, result.Allowed)
+	assert.True(t, result.ResetTime.After(time.Now().Add(59*time.Minute)), "over the configured limit blocks")
+}
@@ -0,0 +1,57 @@
+package ratelimiter
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// AuditInfo describes the request a check is made for, so block events can
+// be traced back to it.
+type AuditInfo struct {
+	Route     string
+	RequestID string
+}
+
+type auditInfoKey struct{}
+
+// ContextWithAuditInfo attaches info to ctx; checks made with ctx include it
+// in their audit events.
+func ContextWithAuditInfo(ctx context.Context, info AuditInfo) context.Context {
+	return context.WithValue(ctx, auditInfoKey{}, info)
+}
+
+// WithAuditLogger logs an event every time a key gets blocked, with the key,
+// limit type, count, limit, block expiry and, when set on the context, the
+// route and request ID. Keys contain the identity, so tokens are only kept
+// out of the log when they are hashed, see WithTokenHashSecret.
+func WithAuditLogger(logger *slog.Logger) Option {
+	return func(rl *RateLimiter) {
+		rl.audit = logger
+	}
+}
+
+func (rl *RateLimiter) auditBlock(ctx context.Context, key string, limitType LimitType, count int64, config Config, until time.Time) {
+	if rl.audit == nil {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("key", key),
+		slog.String("limit_type", string(limitType)),
+		slog.Int64("count", count),
+		slog.Int64("limit", config.Limit),
+		slog.String("window", config.Window.String()),
+		slog.Time("blocked_until", until),
+	}
+	if info, ok := ctx.Value(auditInfoKey{}).(AuditInfo); ok {
+		if info.Route != "" {
+			attrs = append(attrs, slog.String("route", info.Route))
+		}
+		if info.RequestID != "" {
+			attrs = append(attrs, slog.String("request_id", info.RequestID))
+		}
+	}
+
+	rl.audit.LogAttrs(ctx, slog.LevelInfo, "rate limit block", attrs...)
+}
@@ -0,0 +1,63 @@
+package ratelimiter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter_AuditLogsBlocks(t *testing.T) {
+	var buf bytes.Buffer
+	rateLimiter, fake := newFakeClockRateLimiter(Config{Limit: 2, Window: time.Second, BlockTime: time.Hour},
+		WithAuditLogger(slog.New(slog.NewJSONHandler(&buf, nil))),
+	)
+	ctx := ContextWithAuditInfo(context.Background(), AuditInfo{Route: "GET /api/test", RequestID: "req-1"})
+
+	for i := 0; i < 4; i++ {
+		_, err := rateLimiter.CheckLimit(ctx, "192.168.1.1", "")
+		require.NoError(t, err)
+	}
+
+	// Only the check that starts the block is logged.
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 1)
+
+	var event map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &event))
+	assert.Equal(t, "rate limit block", event["msg"])
+	assert.Equal(t, "ip:192.168.1.1", event["key"])
+	assert.Equal(t, "ip", event["limit_type"])
+	assert.Equal(t, float64(3), event["count"])
+	assert.Equal(t, float64(2), event["limit"])
+	assert.Equal(t, fake.Now().Add(time.Hour).Format(time.RFC3339), event["blocked_until"])
+	assert.Equal(t, "GET /api/test", event["route"])
+	assert.Equal(t, "req-1", event["request_id"])
+}
+
+func TestRateLimiter_AuditWithoutInfo(t *testing.T) {
+	var buf bytes.Buffer
+	rateLimiter, _ := newFakeClockRateLimiter(Config{Limit: 1, Window: time.Second, BlockTime: time.Minute},
+		WithAuditLogger(slog.New(slog.NewJSONHandler(&buf, nil))),
+		WithTokenHashSecret([]byte("secret")),
+		WithHashedTokenConfig(HashToken([]byte("secret"), "abc123"), Config{Limit: 1, Window: time.Second, BlockTime: time.Minute}),
+	)
+
+	for i := 0; i < 2; i++ {
+		_, err := rateLimiter.CheckLimit(context.Background(), "192.168.1.1", "abc123")
+		require.NoError(t, err)
+	}
+
+	var event map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &event))
+	assert.Equal(t, "token", event["limit_type"])
+	assert.NotContains(t, buf.String(), "abc123")
+	assert.NotContains(t, event, "route")
+	assert.NotContains(t, event, "request_id")
+}
@@ -0,0 +1,134 @@
+package ratelimiter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tiago-kimura/rate-limiter/pkg/clock"
+	"github.com/tiago-kimura/rate-limiter/pkg/storage"
+)
+
+// BandwidthConfig limits the bytes an identity may transfer, request bodies
+// and responses together, per Window. BytesPerSecond optionally caps the
+// speed of each response. A non-positive Limit sets no budget, so that
+// responses can be throttled without one.
+type BandwidthConfig struct {
+	Limit          int64
+	Window         time.Duration
+	BytesPerSecond int64
+}
+
+// BandwidthLimiter keeps a byte budget per identity. Bytes are recorded once
+// a request completes, so a single large response may overshoot the budget;
+// the requests that follow are then rejected until the window resets.
+type BandwidthLimiter struct {
+	storage     storage.Storage
+	rateLimiter *RateLimiter
+	config      BandwidthConfig
+	clock       clock.Clock
+}
+
+type BandwidthOption func(*BandwidthLimiter)
+
+// WithBandwidthClock computes reset times from the given clock instead of the
+// system clock.
+func WithBandwidthClock(c clock.Clock) BandwidthOption {
+	return func(bl *BandwidthLimiter) {
+		bl.clock = c
+	}
+}
+
+// NewBandwidthLimiter keeps a budget for the identity rateLimiter applies its
+// limit to, with tokens hashed by its secret.
+func NewBandwidthLimiter(storage storage.Storage, rateLimiter *RateLimiter, config BandwidthConfig, opts ...BandwidthOption) *BandwidthLimiter {
+	bl := &BandwidthLimiter{
+		storage:     storage,
+		rateLimiter: rateLimiter,
+		config:      config,
+		clock:       clock.Real,
+	}
+
+	for _, opt := range opts {
+		opt(bl)
+	}
+
+	return bl
+}
+
+func (bl *BandwidthLimiter) BytesPerSecond() int64 {
+	return bl.config.BytesPerSecond
+}
+
+// Budgeted reports whether the limiter keeps a byte budget, rather than only
+// throttling responses.
+func (bl *BandwidthLimiter) Budgeted() bool {
+	return bl.config.Limit > 0
+}
+
+// BandwidthUsage is the state of an identity's byte budget before a request.
+type BandwidthUsage struct {
+	Allowed   bool
+	Used      int64
+	Limit     int64
+	ResetTime time.Time
+	LimitType LimitType
+
+	key string
+}
+
+func (u *BandwidthUsage) Remaining() int64 {
+	if remaining := u.Limit - u.Used; remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// Check reports whether the identity the RateLimiter would check has bytes
+// left in the current window. It does not count anything; see Record.
+func (bl *BandwidthLimiter) Check(ctx context.Context, ids ...Identity) (*BandwidthUsage, error) {
+	if !bl.Budgeted() {
+		return &BandwidthUsage{Allowed: true}, nil
+	}
+
+	id, err := bl.rateLimiter.Identify(ctx, ids...)
+	if err != nil {
+		return nil, err
+	}
+
+	key := fmt.Sprintf("bytes:%s", id.Key())
+	used, err := bl.storage.Get(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bandwidth usage: %w", err)
+	}
+
+	ttl, err := bl.storage.TTL(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bandwidth TTL: %w", err)
+	}
+	if ttl <= 0 {
+		ttl = bl.config.Window
+	}
+
+	return &BandwidthUsage{
+		Allowed:   used < bl.config.Limit,
+		Used:      used,
+		Limit:     bl.config.Limit,
+		ResetTime: bl.clock.Now().Add(ttl),
+		LimitType: id.Type,
+		key:       key,
+	}, nil
+}
+
+// Record counts bytes transferred by a request against the budget Check was
+// called for.
+func (bl *BandwidthLimiter) Record(ctx context.Context, usage *BandwidthUsage, bytes int64) error {
+	if bytes <= 0 || usage.key == "" {
+		return nil
+	}
+
+	if _, err := bl.storage.IncrementBy(ctx, usage.key, bytes, bl.config.Window); err != nil {
+		return fmt.Errorf("failed to record bandwidth usage: %w", err)
+	}
+	return nil
+}
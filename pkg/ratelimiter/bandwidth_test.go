@@ -0,0 +1,125 @@
+package ratelimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tiago-kimura/rate-limiter/pkg/clock"
+	"github.com/tiago-kimura/rate-limiter/pkg/storage"
+)
+
+func newBandwidthLimiter(store storage.Storage, config BandwidthConfig, opts ...Option) *BandwidthLimiter {
+	rateLimiter := NewRateLimiter(store, Config{Limit: 100, Window: time.Second, BlockTime: time.Minute}, opts...)
+	return NewBandwidthLimiter(store, rateLimiter, config)
+}
+
+func TestBandwidthLimiter_Budget(t *testing.T) {
+	limiter := newBandwidthLimiter(storage.NewMockStorage(), BandwidthConfig{Limit: 1000, Window: time.Minute})
+	ctx := context.Background()
+	id := Identity{Type: IPLimit, Value: "192.168.1.1"}
+
+	usage, err := limiter.Check(ctx, id)
+	require.NoError(t, err)
+	assert.True(t, usage.Allowed)
+	assert.Equal(t, int64(1000), usage.Remaining())
+
+	require.NoError(t, limiter.Record(ctx, usage, 600))
+
+	usage, err = limiter.Check(ctx, id)
+	require.NoError(t, err)
+	assert.True(t, usage.Allowed)
+	assert.Equal(t, int64(400), usage.Remaining())
+
+	require.NoError(t, limiter.Record(ctx, usage, 600))
+
+	usage, err = limiter.Check(ctx, id)
+	require.NoError(t, err)
+	assert.False(t, usage.Allowed)
+	assert.Equal(t, int64(0), usage.Remaining())
+	assert.WithinDuration(t, time.Now().Add(time.Minute), usage.ResetTime, time.Second)
+
+	usage, err = limiter.Check(ctx, Identity{Type: IPLimit, Value: "192.168.1.2"})
+	require.NoError(t, err)
+	assert.True(t, usage.Allowed)
+}
+
+func TestBandwidthLimiter_WindowReset(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	store := storage.NewMockStorage(storage.WithMockClock(fake))
+	rateLimiter := NewRateLimiter(store, Config{Limit: 100, Window: time.Second, BlockTime: time.Minute}, WithClock(fake))
+	limiter := NewBandwidthLimiter(store, rateLimiter, BandwidthConfig{Limit: 1000, Window: time.Minute}, WithBandwidthClock(fake))
+	ctx := context.Background()
+	id := Identity{Type: IPLimit, Value: "192.168.1.1"}
+
+	usage, err := limiter.Check(ctx, id)
+	require.NoError(t, err)
+	require.NoError(t, limiter.Record(ctx, usage, 1000))
+
+	fake.Advance(20 * time.Second)
+	usage, err = limiter.Check(ctx, id)
+	require.NoError(t, err)
+	assert.False(t, usage.Allowed)
+	assert.Equal(t, fake.Now().Add(40*time.Second), usage.ResetTime)
+
+	fake.Advance(40 * time.Second)
+	usage, err = limiter.Check(ctx, id)
+	require.NoError(t, err)
+	assert.True(t, usage.Allowed)
+	assert.Equal(t, int64(1000), usage.Remaining())
+}
+
+func TestBandwidthLimiter_NoBudget(t *testing.T) {
+	store := storage.NewMockStorage()
+	limiter := newBandwidthLimiter(store, BandwidthConfig{Window: time.Minute, BytesPerSecond: 1000})
+	ctx := context.Background()
+	id := Identity{Type: IPLimit, Value: "192.168.1.1"}
+	assert.False(t, limiter.Budgeted())
+
+	for i := 0; i < 3; i++ {
+		usage, err := limiter.Check(ctx, id)
+		require.NoError(t, err)
+		assert.True(t, usage.Allowed)
+		require.NoError(t, limiter.Record(ctx, usage, 1<<20))
+	}
+
+	used, err := store.Get(ctx, "bytes:ip:192.168.1.1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), used)
+}
+
+func TestBandwidthLimiter_HashesTokens(t *testing.T) {
+	store := &keyRecordingStorage{Storage: storage.NewMockStorage()}
+	limiter := newBandwidthLimiter(store, BandwidthConfig{Limit: 1000, Window: time.Minute},
+		WithTokenHashSecret([]byte("secret")),
+		WithTokenConfig("abc123", Config{Limit: 100, Window: time.Second, BlockTime: time.Minute}),
+	)
+	ctx := context.Background()
+
+	usage, err := limiter.Check(ctx, Identity{Type: TokenLimit, Value: "abc123"})
+	require.NoError(t, err)
+	require.NoError(t, limiter.Record(ctx, usage, 10))
+
+	for _, key := range store.keys {
+		assert.NotContains(t, key, "abc123")
+	}
+	assert.Contains(t, store.keys, "bytes:token:"+HashToken([]byte("secret"), "abc123"))
+}
+
+func TestBandwidthLimiter_UnknownTokensFallThroughToIP(t *testing.T) {
+	limiter := newBandwidthLimiter(storage.NewMockStorage(), BandwidthConfig{Limit: 1000, Window: time.Minute})
+	ctx := context.Background()
+	ip := Identity{Type: IPLimit, Value: "192.168.1.1"}
+
+	usage, err := limiter.Check(ctx, Identity{Type: TokenLimit, Value: "random-1"}, ip)
+	require.NoError(t, err)
+	assert.Equal(t, IPLimit, usage.LimitType)
+	require.NoError(t, limiter.Record(ctx, usage, 1000))
+
+	usage, err = limiter.Check(ctx, Identity{Type: TokenLimit, Value: "random-2"}, ip)
+	require.NoError(t, err)
+	assert.Equal(t, IPLimit, usage.LimitType)
+	assert.False(t, usage.Allowed)
+}
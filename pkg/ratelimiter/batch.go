@@ -0,0 +1,80 @@
+package ratelimiter
+
+import (
+	"context"
+	"sync"
+)
+
+// maxParallelChecks bounds the goroutines a CheckLimits call runs at once.
+const maxParallelChecks = 64
+
+// LimitCheck is one check of a CheckLimits call, equivalent to
+// CheckN(ctx, Cost, Identities...).
+type LimitCheck struct {
+	Cost       int64
+	Identities []Identity
+}
+
+// LimitCheckResult is the outcome of a LimitCheck; Err is set instead of
+// Result when the check failed.
+type LimitCheckResult struct {
+	Result *CheckResult
+	Err    error
+}
+
+// CheckLimits runs checks concurrently and returns their results in the same
+// order. Checks counted against the same key run one after another in
+// request order, so the earlier ones are allowed first. With a storage that
+// batches commands, such as a RedisStorage created with
+// storage.WithBatching, the storage calls of all keys share a few pipelines
+// instead of taking a round trip each.
+func (rl *RateLimiter) CheckLimits(ctx context.Context, checks []LimitCheck) []LimitCheckResult {
+	results := make([]LimitCheckResult, len(checks))
+	ids := make([]Identity, len(checks))
+	configs := make([]Config, len(checks))
+
+	parallel(len(checks), func(i int) {
+		ids[i], configs[i], results[i].Err = rl.identify(ctx, checks[i].Identities)
+	})
+
+	var groups [][]int
+	groupOf := make(map[string]int)
+	for i := range checks {
+		if results[i].Err != nil {
+			continue
+		}
+		key := ids[i].Key()
+		g, exists := groupOf[key]
+		if !exists {
+			g = len(groups)
+			groupOf[key] = g
+			groups = append(groups, nil)
+		}
+		groups[g] = append(groups[g], i)
+	}
+
+	parallel(len(groups), func(g int) {
+		for _, i := range groups[g] {
+			results[i].Result, results[i].Err = rl.checkIdentity(ctx, ids[i], configs[i], checks[i].Cost)
+		}
+	})
+
+	return results
+}
+
+// parallel calls fn for 0 through n-1 on at most maxParallelChecks
+// goroutines at once and waits for them.
+func parallel(n int, fn func(i int)) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxParallelChecks)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,67 @@
+package ratelimiter
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tiago-kimura/rate-limiter/pkg/storage"
+)
+
+func TestRateLimiter_CheckLimits(t *testing.T) {
+	rateLimiter := NewRateLimiter(storage.NewMockStorage(), Config{Limit: 5, Window: time.Minute, BlockTime: time.Minute})
+	ctx := context.Background()
+
+	checks := []LimitCheck{
+		{Cost: 2, Identities: []Identity{{Type: IPLimit, Value: "192.168.1.1"}}},
+		{Cost: 6, Identities: []Identity{{Type: IPLimit, Value: "192.168.1.2"}}},
+		{Cost: 1, Identities: []Identity{{Type: IPLimit}}},
+	}
+	for i := 0; i < 100; i++ {
+		checks = append(checks, LimitCheck{Cost: 1, Identities: []Identity{{Type: HeaderLimit, Value: "tenant"}}})
+	}
+
+	results := rateLimiter.CheckLimits(ctx, checks)
+	require.Len(t, results, len(checks))
+
+	require.NoError(t, results[0].Err)
+	assert.True(t, results[0].Result.Allowed)
+	assert.Equal(t, int64(3), results[0].Result.Remaining)
+
+	require.NoError(t, results[1].Err)
+	assert.False(t, results[1].Result.Allowed)
+
+	assert.ErrorIs(t, results[2].Err, ErrNoIdentity)
+
+	allowed := 0
+	for _, res := range results[3:] {
+		require.NoError(t, res.Err)
+		if res.Result.Allowed {
+			allowed++
+		}
+	}
+	assert.Equal(t, 5, allowed)
+}
+
+func TestRateLimiter_CheckLimitsKeepsOrderPerKey(t *testing.T) {
+	rateLimiter := NewRateLimiter(storage.NewMockStorage(), Config{Limit: 5, Window: time.Minute, BlockTime: time.Minute})
+	ctx := context.Background()
+
+	// The second check resolves to the same key as the first one through
+	// an unknown token falling through to the IP.
+	for i := 0; i < 20; i++ {
+		ip := Identity{Type: IPLimit, Value: fmt.Sprintf("192.168.1.%d", i)}
+		results := rateLimiter.CheckLimits(ctx, []LimitCheck{
+			{Cost: 5, Identities: []Identity{ip}},
+			{Cost: 1, Identities: []Identity{{Type: TokenLimit, Value: "unknown"}, ip}},
+		})
+
+		require.NoError(t, results[0].Err)
+		assert.True(t, results[0].Result.Allowed)
+		require.NoError(t, results[1].Err)
+		assert.False(t, results[1].Result.Allowed)
+	}
+}
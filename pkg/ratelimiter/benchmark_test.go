@@ -0,0 +1,96 @@
+package ratelimiter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/require"
+	"github.com/tiago-kimura/rate-limiter/pkg/storage"
+)
+
+// benchmarkBackends are the storages CheckLimit is benchmarked against,
+// closed when the benchmark ends. The Redis ones run on miniredis unless
+// BENCH_REDIS_URL points to a real Redis. miniredis runs every Lua script in
+// a fresh interpreter, so its numbers say little about a production Redis.
+var benchmarkBackends = []struct {
+	name string
+	new  func(b *testing.B) storage.Storage
+}{
+	{"Mock", func(b *testing.B) storage.Storage {
+		return storage.NewMockStorage()
+	}},
+	{"Bolt", func(b *testing.B) storage.Storage {
+		store, err := storage.NewBoltStorage(filepath.Join(b.TempDir(), "limits.db"))
+		require.NoError(b, err)
+		b.Cleanup(func() { store.Close() })
+		return store
+	}},
+	{"Redis", func(b *testing.B) storage.Storage {
+		return newBenchmarkRedis(b)
+	}},
+	{"RedisBatched", func(b *testing.B) storage.Storage {
+		return newBenchmarkRedis(b, storage.WithBatching(200*time.Microsecond, 100))
+	}},
+}
+
+func newBenchmarkRedis(b *testing.B, opts ...storage.RedisOption) storage.Storage {
+	url := os.Getenv("BENCH_REDIS_URL")
+	if url == "" {
+		url = "redis://" + miniredis.RunT(b).Addr() + "/0"
+	}
+
+	store, err := storage.NewRedisStorage(url, opts...)
+	require.NoError(b, err)
+	b.Cleanup(func() { store.Close() })
+
+	// Every run starts from empty counters, also on a shared Redis.
+	namespace := fmt.Sprintf("bench-%d", time.Now().UnixNano())
+	namespaced, err := storage.NewNamespacedStorage(store, namespace)
+	require.NoError(b, err)
+	b.Cleanup(func() { namespaced.Purge(context.Background()) })
+	return namespaced
+}
+
+// BenchmarkCheckLimit checks 100 IPs round robin with a limit high enough
+// that nothing is blocked.
+func BenchmarkCheckLimit(b *testing.B) {
+	config := Config{Limit: 1 << 40, Window: time.Hour, BlockTime: time.Minute}
+
+	for _, backend := range benchmarkBackends {
+		b.Run(backend.name, func(b *testing.B) {
+			benchmarkCheckLimit(b, NewRateLimiter(backend.new(b), config))
+		})
+
+		b.Run(backend.name+"LocalCache", func(b *testing.B) {
+			benchmarkCheckLimit(b, NewRateLimiter(backend.new(b), config, WithLocalCache(LocalCacheConfig{BatchSize: 100})))
+		})
+	}
+}
+
+func benchmarkCheckLimit(b *testing.B, rl *RateLimiter) {
+	ips := make([]string, 100)
+	for i := range ips {
+		ips[i] = fmt.Sprintf("10.0.0.%d", i)
+	}
+
+	ctx := context.Background()
+	var next atomic.Int64
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			ip := ips[next.Add(1)%int64(len(ips))]
+			if _, err := rl.CheckLimit(ctx, ip, ""); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
@@ -0,0 +1,154 @@
+package ratelimiter
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/tiago-kimura/rate-limiter/pkg/storage"
+)
+
+const DefaultLeaseTTL = time.Minute
+
+// ConcurrencyConfig limits the number of requests an identity may have in
+// flight at once. LeaseTTL bounds how long a slot is held when it is never
+// released, e.g. because the instance holding it crashed.
+type ConcurrencyConfig struct {
+	Limit    int64
+	LeaseTTL time.Duration
+}
+
+// ConcurrencyLimiter limits in-flight requests per identity. Unlike
+// RateLimiter it does not count requests over a window; a slot is taken with
+// Acquire and given back with Lease.Release.
+type ConcurrencyLimiter struct {
+	storage     storage.LeaseStorage
+	rateLimiter *RateLimiter
+	config      ConcurrencyConfig
+	typeConfigs map[LimitType]ConcurrencyConfig
+}
+
+type ConcurrencyOption func(*ConcurrencyLimiter)
+
+// WithConcurrencyTypeConfig sets the concurrency limit applied to identities
+// of the given type instead of the default one.
+func WithConcurrencyTypeConfig(limitType LimitType, config ConcurrencyConfig) ConcurrencyOption {
+	return func(cl *ConcurrencyLimiter) {
+		cl.typeConfigs[limitType] = config
+	}
+}
+
+// NewConcurrencyLimiter limits the identity rateLimiter applies its limit
+// to, with tokens hashed by its secret.
+func NewConcurrencyLimiter(storage storage.LeaseStorage, rateLimiter *RateLimiter, config ConcurrencyConfig, opts ...ConcurrencyOption) *ConcurrencyLimiter {
+	cl := &ConcurrencyLimiter{
+		storage:     storage,
+		rateLimiter: rateLimiter,
+		config:      config,
+		typeConfigs: make(map[LimitType]ConcurrencyConfig),
+	}
+
+	for _, opt := range opts {
+		opt(cl)
+	}
+
+	return cl
+}
+
+// Lease is a slot taken by Acquire. A lease that was not allowed holds no
+// slot and releasing it is a no-op. The slot is given up after TTL unless
+// the lease is renewed.
+type Lease struct {
+	Allowed   bool
+	InFlight  int64
+	Limit     int64
+	LimitType LimitType
+	TTL       time.Duration
+
+	storage storage.LeaseStorage
+	key     string
+	id      string
+}
+
+func (l *Lease) Remaining() int64 {
+	if remaining := l.Limit - l.InFlight; remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// Renew moves the lease's expiry to TTL from now, for requests that run
+// longer than TTL. It returns false when the slot was already given up.
+func (l *Lease) Renew(ctx context.Context) (bool, error) {
+	if !l.Allowed {
+		return false, nil
+	}
+
+	renewed, err := l.storage.RenewLease(ctx, l.key, l.id, l.TTL)
+	if err != nil {
+		return false, fmt.Errorf("failed to renew lease: %w", err)
+	}
+	return renewed, nil
+}
+
+func (l *Lease) Release(ctx context.Context) error {
+	if !l.Allowed {
+		return nil
+	}
+
+	if err := l.storage.ReleaseLease(ctx, l.key, l.id); err != nil {
+		return fmt.Errorf("failed to release lease: %w", err)
+	}
+	return nil
+}
+
+// Acquire takes a slot for the identity the RateLimiter would check.
+func (cl *ConcurrencyLimiter) Acquire(ctx context.Context, ids ...Identity) (*Lease, error) {
+	id, err := cl.rateLimiter.Identify(ctx, ids...)
+	if err != nil {
+		return nil, err
+	}
+
+	config, exists := cl.typeConfigs[id.Type]
+	if !exists {
+		config = cl.config
+	}
+	if config.LeaseTTL <= 0 {
+		config.LeaseTTL = DefaultLeaseTTL
+	}
+
+	return cl.acquire(ctx, fmt.Sprintf("inflight:%s", id.Key()), config, id.Type)
+}
+
+func (cl *ConcurrencyLimiter) acquire(ctx context.Context, key string, config ConcurrencyConfig, limitType LimitType) (*Lease, error) {
+	id, err := newLeaseID()
+	if err != nil {
+		return nil, err
+	}
+
+	acquired, inFlight, err := cl.storage.AcquireLease(ctx, key, id, config.Limit, config.LeaseTTL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire lease: %w", err)
+	}
+
+	return &Lease{
+		Allowed:   acquired,
+		InFlight:  inFlight,
+		Limit:     config.Limit,
+		LimitType: limitType,
+		TTL:       config.LeaseTTL,
+		storage:   cl.storage,
+		key:       key,
+		id:        id,
+	}, nil
+}
+
+func newLeaseID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate lease id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
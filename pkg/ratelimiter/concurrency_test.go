@@ -0,0 +1,143 @@
+package ratelimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tiago-kimura/rate-limiter/pkg/clock"
+	"github.com/tiago-kimura/rate-limiter/pkg/storage"
+)
+
+func newConcurrencyLimiter(store *storage.MockStorage, config ConcurrencyConfig, opts ...ConcurrencyOption) *ConcurrencyLimiter {
+	rateLimiter := NewRateLimiter(store, Config{Limit: 100, Window: time.Second, BlockTime: time.Minute})
+	return NewConcurrencyLimiter(store, rateLimiter, config, opts...)
+}
+
+func TestConcurrencyLimiter_AcquireRelease(t *testing.T) {
+	limiter := newConcurrencyLimiter(storage.NewMockStorage(), ConcurrencyConfig{Limit: 2, LeaseTTL: time.Minute})
+	ctx := context.Background()
+	id := Identity{Type: IPLimit, Value: "192.168.1.1"}
+
+	first, err := limiter.Acquire(ctx, id)
+	require.NoError(t, err)
+	assert.True(t, first.Allowed)
+	assert.Equal(t, int64(1), first.Remaining())
+
+	second, err := limiter.Acquire(ctx, id)
+	require.NoError(t, err)
+	assert.True(t, second.Allowed)
+	assert.Equal(t, int64(0), second.Remaining())
+
+	denied, err := limiter.Acquire(ctx, id)
+	require.NoError(t, err)
+	assert.False(t, denied.Allowed)
+	assert.Equal(t, IPLimit, denied.LimitType)
+	assert.NoError(t, denied.Release(ctx))
+
+	other, err := limiter.Acquire(ctx, Identity{Type: IPLimit, Value: "192.168.1.2"})
+	require.NoError(t, err)
+	assert.True(t, other.Allowed)
+
+	require.NoError(t, first.Release(ctx))
+
+	again, err := limiter.Acquire(ctx, id)
+	require.NoError(t, err)
+	assert.True(t, again.Allowed)
+}
+
+func TestConcurrencyLimiter_LeaseExpiry(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	limiter := newConcurrencyLimiter(storage.NewMockStorage(storage.WithMockClock(fake)), ConcurrencyConfig{Limit: 1, LeaseTTL: time.Minute})
+	ctx := context.Background()
+	id := Identity{Type: IPLimit, Value: "192.168.1.1"}
+
+	leaked, err := limiter.Acquire(ctx, id)
+	require.NoError(t, err)
+	require.True(t, leaked.Allowed)
+
+	lease, err := limiter.Acquire(ctx, id)
+	require.NoError(t, err)
+	assert.False(t, lease.Allowed)
+
+	fake.Advance(time.Minute)
+
+	lease, err = limiter.Acquire(ctx, id)
+	require.NoError(t, err)
+	assert.True(t, lease.Allowed)
+}
+
+func TestConcurrencyLimiter_Renew(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	limiter := newConcurrencyLimiter(storage.NewMockStorage(storage.WithMockClock(fake)), ConcurrencyConfig{Limit: 1, LeaseTTL: time.Minute})
+	ctx := context.Background()
+	id := Identity{Type: IPLimit, Value: "192.168.1.1"}
+
+	lease, err := limiter.Acquire(ctx, id)
+	require.NoError(t, err)
+	require.True(t, lease.Allowed)
+	assert.Equal(t, time.Minute, lease.TTL)
+
+	fake.Advance(40 * time.Second)
+	renewed, err := lease.Renew(ctx)
+	require.NoError(t, err)
+	assert.True(t, renewed)
+
+	fake.Advance(40 * time.Second)
+	denied, err := limiter.Acquire(ctx, id)
+	require.NoError(t, err)
+	assert.False(t, denied.Allowed)
+
+	renewed, err = denied.Renew(ctx)
+	require.NoError(t, err)
+	assert.False(t, renewed)
+
+	fake.Advance(time.Minute)
+	renewed, err = lease.Renew(ctx)
+	require.NoError(t, err)
+	assert.False(t, renewed)
+}
+
+func TestConcurrencyLimiter_TypeConfigAndIdentities(t *testing.T) {
+	store := storage.NewMockStorage()
+	rateLimiter := NewRateLimiter(store, Config{Limit: 100, Window: time.Second, BlockTime: time.Minute},
+		WithTokenConfig("abc123", Config{Limit: 100, Window: time.Second, BlockTime: time.Minute}),
+	)
+	limiter := NewConcurrencyLimiter(store, rateLimiter, ConcurrencyConfig{Limit: 1},
+		WithConcurrencyTypeConfig(TokenLimit, ConcurrencyConfig{Limit: 5}),
+	)
+	ctx := context.Background()
+
+	lease, err := limiter.Acquire(ctx, Identity{Type: TokenLimit}, Identity{Type: IPLimit, Value: "192.168.1.1"})
+	require.NoError(t, err)
+	assert.Equal(t, IPLimit, lease.LimitType)
+	assert.Equal(t, int64(1), lease.Limit)
+
+	lease, err = limiter.Acquire(ctx, Identity{Type: TokenLimit, Value: "abc123"})
+	require.NoError(t, err)
+	assert.Equal(t, TokenLimit, lease.LimitType)
+	assert.Equal(t, int64(5), lease.Limit)
+
+	_, err = limiter.Acquire(ctx, Identity{Type: IPLimit})
+	assert.ErrorIs(t, err, ErrNoIdentity)
+}
+
+func TestConcurrencyLimiter_UnknownTokensFallThroughToIP(t *testing.T) {
+	limiter := newConcurrencyLimiter(storage.NewMockStorage(), ConcurrencyConfig{Limit: 1},
+		WithConcurrencyTypeConfig(TokenLimit, ConcurrencyConfig{Limit: 5}),
+	)
+	ctx := context.Background()
+	ip := Identity{Type: IPLimit, Value: "192.168.1.1"}
+
+	lease, err := limiter.Acquire(ctx, Identity{Type: TokenLimit, Value: "random-1"}, ip)
+	require.NoError(t, err)
+	assert.True(t, lease.Allowed)
+	assert.Equal(t, IPLimit, lease.LimitType)
+
+	lease, err = limiter.Acquire(ctx, Identity{Type: TokenLimit, Value: "random-2"}, ip)
+	require.NoError(t, err)
+	assert.False(t, lease.Allowed)
+	assert.Equal(t, IPLimit, lease.LimitType)
+}
@@ -0,0 +1,16 @@
+// Package ratelimiter implements fixed-window rate limiting by IP address or
+// access token on top of a storage.Storage backend.
+//
+// A limiter is built with NewRateLimiter and configured through functional
+// options:
+//
+//	rl := ratelimiter.NewRateLimiter(store, ratelimiter.Config{
+//		Limit:     10,
+//		Window:    time.Second,
+//		BlockTime: 5 * time.Minute,
+//	}, ratelimiter.WithTokenConfig("abc123", ratelimiter.Config{
+//		Limit:     100,
+//		Window:    time.Second,
+//		BlockTime: time.Minute,
+//	}))
+package ratelimiter
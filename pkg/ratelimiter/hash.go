@@ -0,0 +1,65 @@
+package ratelimiter
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// HashToken returns the hex encoded HMAC-SHA256 of token. With a hash secret
+// configured, the limiter only ever stores and reports this value.
+func HashToken(secret []byte, token string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(token))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// WithTokenHashSecret makes the limiter HMAC-hash tokens before they are used
+// in storage keys or passed to the TierResolver, which must then be keyed by
+// HashToken values. Configs set with WithTokenConfig are hashed as well.
+//
+// Headers, cookies and query parameters may carry credentials too, so their
+// values are hashed as well, as are combined identities including one.
+func WithTokenHashSecret(secret []byte) Option {
+	return func(rl *RateLimiter) {
+		rl.hashSecret = secret
+	}
+}
+
+// WithHashedTokenConfig sets the limit for the token whose HashToken value is
+// hash, so raw tokens don't have to appear in the configuration. It only
+// applies together with WithTokenHashSecret.
+func WithHashedTokenConfig(hash string, config Config) Option {
+	return func(rl *RateLimiter) {
+		rl.hashedTokenConfigs[hash] = config
+	}
+}
+
+// hashesValue reports whether values of identities of limitType, or of a
+// CombineIdentities type made up of it, are hashed with the secret.
+func hashesValue(limitType LimitType) bool {
+	for _, part := range strings.Split(string(limitType), "+") {
+		switch LimitType(part) {
+		case TokenLimit, HeaderLimit, CookieLimit, QueryLimit:
+			return true
+		}
+	}
+	return false
+}
+
+func (rl *RateLimiter) hashTokenConfigs() {
+	if rl.hashSecret == nil {
+		return
+	}
+
+	configs := make(map[string]Config, len(rl.tokenConfigs)+len(rl.hashedTokenConfigs))
+	for token, config := range rl.tokenConfigs {
+		configs[HashToken(rl.hashSecret, token)] = config
+	}
+	for hash, config := range rl.hashedTokenConfigs {
+		configs[hash] = config
+	}
+
+	rl.tokenConfigs = configs
+}
@@ -0,0 +1,123 @@
+package ratelimiter
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tiago-kimura/rate-limiter/pkg/storage"
+)
+
+type keyRecordingStorage struct {
+	storage.Storage
+	keys []string
+}
+
+func (s *keyRecordingStorage) Get(ctx context.Context, key string) (int64, error) {
+	s.keys = append(s.keys, key)
+	return s.Storage.Get(ctx, key)
+}
+
+func (s *keyRecordingStorage) IncrementBy(ctx context.Context, key string, value int64, expiration time.Duration) (int64, error) {
+	s.keys = append(s.keys, key)
+	return s.Storage.IncrementBy(ctx, key, value, expiration)
+}
+
+func (s *keyRecordingStorage) Set(ctx context.Context, key string, count int64, expiration time.Duration) error {
+	s.keys = append(s.keys, key)
+	return s.Storage.Set(ctx, key, count, expiration)
+}
+
+func TestHashToken(t *testing.T) {
+	hash := HashToken([]byte("secret"), "abc123")
+	assert.Len(t, hash, 64)
+	assert.Equal(t, hash, HashToken([]byte("secret"), "abc123"))
+	assert.NotEqual(t, hash, HashToken([]byte("other"), "abc123"))
+}
+
+func TestRateLimiter_HashedTokensInStorage(t *testing.T) {
+	recording := &keyRecordingStorage{Storage: storage.NewMockStorage()}
+	secret := []byte("secret")
+
+	rateLimiter := NewRateLimiter(recording, Config{Limit: 1, Window: time.Second, BlockTime: time.Minute},
+		WithTokenConfig("raw_token", Config{Limit: 1, Window: time.Second, BlockTime: time.Minute}),
+		WithTokenHashSecret(secret),
+	)
+	ctx := context.Background()
+
+	result, err := rateLimiter.CheckLimit(ctx, "192.168.1.1", "raw_token")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, TokenLimit, result.LimitType)
+
+	result, err = rateLimiter.CheckLimit(ctx, "192.168.1.1", "raw_token")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+
+	require.NotEmpty(t, recording.keys)
+	for _, key := range recording.keys {
+		assert.False(t, strings.Contains(key, "raw_token"), "key %q leaks the raw token", key)
+	}
+	assert.Contains(t, recording.keys, "token:"+HashToken(secret, "raw_token"))
+	assert.Contains(t, recording.keys, "blocked:token:"+HashToken(secret, "raw_token"))
+}
+
+func TestRateLimiter_HashesCredentialsOfEveryType(t *testing.T) {
+	recording := &keyRecordingStorage{Storage: storage.NewMockStorage()}
+	secret := []byte("secret")
+
+	rateLimiter := NewRateLimiter(recording, Config{Limit: 10, Window: time.Second, BlockTime: time.Minute},
+		WithTokenHashSecret(secret),
+	)
+	ctx := context.Background()
+	ip := Identity{Type: IPLimit, Value: "192.168.1.1"}
+
+	for _, id := range []Identity{
+		{Type: HeaderLimit, Value: "X-Api-Key=raw_token"},
+		{Type: CookieLimit, Value: "session=raw_token"},
+		{Type: QueryLimit, Value: "api_key=raw_token"},
+		CombineIdentities(Identity{Type: TokenLimit, Value: "raw_token"}, ip),
+	} {
+		result, err := rateLimiter.Check(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, id.Type, result.LimitType)
+	}
+
+	require.NotEmpty(t, recording.keys)
+	for _, key := range recording.keys {
+		assert.False(t, strings.Contains(key, "raw_token"), "key %q leaks the raw token", key)
+	}
+
+	recording.keys = nil
+	_, err := rateLimiter.Check(ctx, ip)
+	require.NoError(t, err)
+	assert.Contains(t, recording.keys, "ip:192.168.1.1")
+}
+
+func TestRateLimiter_HashedTokenReferences(t *testing.T) {
+	secret := []byte("secret")
+	proHash := HashToken(secret, "pro_customer")
+
+	rateLimiter := NewRateLimiter(storage.NewMockStorage(), Config{Limit: 1, Window: time.Second, BlockTime: time.Minute},
+		WithTokenHashSecret(secret),
+		WithHashedTokenConfig(HashToken(secret, "vip"), Config{Limit: 9, Window: time.Second, BlockTime: time.Minute}),
+		WithTierConfig("pro", Config{Limit: 5, Window: time.Second, BlockTime: time.Minute}),
+		WithTierResolver(StaticTiers{proHash: "pro"}),
+	)
+	ctx := context.Background()
+
+	result, err := rateLimiter.CheckLimit(ctx, "192.168.1.1", "vip")
+	require.NoError(t, err)
+	assert.Equal(t, int64(9), result.Limit)
+
+	result, err = rateLimiter.CheckLimit(ctx, "192.168.1.1", "pro_customer")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), result.Limit)
+
+	result, err = rateLimiter.CheckLimit(ctx, "192.168.1.1", proHash)
+	require.NoError(t, err)
+	assert.Equal(t, IPLimit, result.LimitType)
+}
@@ -0,0 +1,234 @@
+package ratelimiter
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/tiago-kimura/rate-limiter/pkg/clock"
+)
+
+const DefaultLocalSyncInterval = 100 * time.Millisecond
+
+// LocalCacheConfig enables an in-process layer in front of the storage.
+// Hits are counted locally and written to storage in batches, and block
+// markers are kept until they expire, so most checks need no storage round
+// trip. The price is bounded over-admission: each instance may let through
+// up to BatchSize-1 extra hits per key and window, and may notice a block
+// set by another instance, or a count reached through other instances,
+// up to SyncInterval late.
+type LocalCacheConfig struct {
+	// BatchSize is the number of hits counted locally for a key before they
+	// are written to storage.
+	BatchSize int64
+	// SyncInterval is the longest hits stay local and storage is not
+	// consulted for a key.
+	SyncInterval time.Duration
+}
+
+// WithLocalCache enables the local cache layer. Call RunLocalCache to write
+// hits of idle keys to storage, and FlushLocalCache before shutting down.
+func WithLocalCache(config LocalCacheConfig) Option {
+	return func(rl *RateLimiter) {
+		if config.BatchSize < 1 {
+			config.BatchSize = 1
+		}
+		if config.SyncInterval <= 0 {
+			config.SyncInterval = DefaultLocalSyncInterval
+		}
+
+		rl.cache = &localCache{
+			config:   config,
+			clock:    clock.Real,
+			blocks:   make(map[string]time.Time),
+			counters: make(map[string]*localCounter),
+		}
+	}
+}
+
+type localCache struct {
+	config LocalCacheConfig
+	clock  clock.Clock
+
+	mu       sync.Mutex
+	blocks   map[string]time.Time
+	counters map[string]*localCounter
+}
+
+type localCounter struct {
+	mu      sync.Mutex
+	window  time.Duration
+	remote  int64
+	pending int64
+	synced  time.Time
+	expiry  time.Time
+}
+
+func (c *localCache) blockedUntil(blockedKey string) (time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	until, exists := c.blocks[blockedKey]
+	if !exists {
+		return time.Time{}, false
+	}
+	if !c.clock.Now().Before(until) {
+		delete(c.blocks, blockedKey)
+		return time.Time{}, false
+	}
+	return until, true
+}
+
+func (c *localCache) block(blockedKey string, until time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.blocks[blockedKey] = until
+}
+
+// fresh reports whether the key was synced with storage recently enough that
+// checking storage for a block can be skipped.
+func (c *localCache) fresh(key string) bool {
+	c.mu.Lock()
+	counter, exists := c.counters[key]
+	c.mu.Unlock()
+	if !exists {
+		return false
+	}
+
+	counter.mu.Lock()
+	defer counter.mu.Unlock()
+	now := c.clock.Now()
+	return now.Before(counter.expiry) && now.Sub(counter.synced) < c.config.SyncInterval
+}
+
+func (c *localCache) counter(key string, window time.Duration) *localCounter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	counter, exists := c.counters[key]
+	if !exists {
+		counter = &localCounter{window: window}
+		c.counters[key] = counter
+	}
+	return counter
+}
+
+// increment counts cost hits for key and returns the estimated total in the
+// current window and its expiry.
+func (c *localCache) increment(ctx context.Context, rl *RateLimiter, key string, cost int64, window time.Duration) (int64, time.Time, error) {
+	counter := c.counter(key, window)
+
+	counter.mu.Lock()
+	defer counter.mu.Unlock()
+
+	now := c.clock.Now()
+	if !now.Before(counter.expiry) {
+		// The window is over; hits still pending belonged to it.
+		counter.remote = 0
+		counter.pending = 0
+		counter.synced = time.Time{}
+		counter.expiry = now.Add(window)
+	}
+
+	counter.pending += cost
+
+	if counter.synced.IsZero() || counter.pending >= c.config.BatchSize || now.Sub(counter.synced) >= c.config.SyncInterval {
+		if err := counter.sync(ctx, rl, key); err != nil {
+			counter.pending -= cost
+			return 0, time.Time{}, err
+		}
+	}
+
+	return counter.remote + counter.pending, counter.expiry, nil
+}
+
+func (counter *localCounter) sync(ctx context.Context, rl *RateLimiter, key string) error {
+	now := rl.clock.Now()
+
+	total, err := rl.storage.IncrementBy(ctx, key, counter.pending, counter.window)
+	if err != nil {
+		return err
+	}
+
+	if total == counter.pending {
+		// This sync created the key, so its window starts now.
+		counter.expiry = now.Add(counter.window)
+	}
+
+	counter.remote = total
+	counter.pending = 0
+	counter.synced = now
+	return nil
+}
+
+// flush writes pending hits to storage and drops expired entries.
+func (c *localCache) flush(ctx context.Context, rl *RateLimiter, all bool) error {
+	now := c.clock.Now()
+
+	c.mu.Lock()
+	for key, until := range c.blocks {
+		if !now.Before(until) {
+			delete(c.blocks, key)
+		}
+	}
+	counters := make(map[string]*localCounter, len(c.counters))
+	for key, counter := range c.counters {
+		counters[key] = counter
+	}
+	c.mu.Unlock()
+
+	var firstErr error
+	for key, counter := range counters {
+		counter.mu.Lock()
+		expired := !now.Before(counter.expiry)
+		if !expired && counter.pending != 0 && (all || now.Sub(counter.synced) >= c.config.SyncInterval) {
+			if err := counter.sync(ctx, rl, key); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+		counter.mu.Unlock()
+
+		if expired {
+			c.mu.Lock()
+			if c.counters[key] == counter {
+				delete(c.counters, key)
+			}
+			c.mu.Unlock()
+		}
+	}
+
+	if firstErr != nil {
+		return fmt.Errorf("failed to sync local counters: %w", firstErr)
+	}
+	return nil
+}
+
+// RunLocalCache writes the hits of keys that were not checked again within
+// SyncInterval to storage, until ctx is done. It does nothing without
+// WithLocalCache.
+func (rl *RateLimiter) RunLocalCache(ctx context.Context) {
+	if rl.cache == nil {
+		return
+	}
+
+	ticker := time.NewTicker(rl.cache.config.SyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			rl.cache.flush(ctx, rl, false)
+		}
+	}
+}
+
+// FlushLocalCache writes all pending hits to storage.
+func (rl *RateLimiter) FlushLocalCache(ctx context.Context) error {
+	if rl.cache == nil {
+		return nil
+	}
+	return rl.cache.flush(ctx, rl, true)
+}
@@ -0,0 +1,136 @@
+package ratelimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tiago-kimura/rate-limiter/pkg/storage"
+)
+
+// countingStorage counts the calls that reach the storage.
+type countingStorage struct {
+	storage.Storage
+	calls int
+}
+
+func (s *countingStorage) Get(ctx context.Context, key string) (int64, error) {
+	s.calls++
+	return s.Storage.Get(ctx, key)
+}
+
+func (s *countingStorage) IncrementBy(ctx context.Context, key string, value int64, expiration time.Duration) (int64, error) {
+	s.calls++
+	return s.Storage.IncrementBy(ctx, key, value, expiration)
+}
+
+func (s *countingStorage) TTL(ctx context.Context, key string) (time.Duration, error) {
+	s.calls++
+	return s.Storage.TTL(ctx, key)
+}
+
+func (s *countingStorage) Set(ctx context.Context, key string, count int64, expiration time.Duration) error {
+	s.calls++
+	return s.Storage.Set(ctx, key, count, expiration)
+}
+
+func TestRateLimiter_LocalCacheBatches(t *testing.T) {
+	store := &countingStorage{Storage: storage.NewMockStorage()}
+	rateLimiter := NewRateLimiter(store,
+		Config{Limit: 100, Window: time.Minute, BlockTime: time.Minute},
+		WithLocalCache(LocalCacheConfig{BatchSize: 10, SyncInterval: time.Minute}),
+	)
+	ctx := context.Background()
+
+	for i := 1; i <= 50; i++ {
+		result, err := rateLimiter.CheckLimit(ctx, "192.168.1.1", "")
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, int64(100-i), result.Remaining)
+	}
+
+	// The first check reads the block marker and syncs, then every 10th
+	// check syncs a batch: after checks 1, 11, 21, 31 and 41.
+	assert.Equal(t, 1+5, store.calls)
+
+	count, err := store.Storage.Get(ctx, "ip:192.168.1.1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(41), count)
+
+	require.NoError(t, rateLimiter.FlushLocalCache(ctx))
+	count, err = store.Storage.Get(ctx, "ip:192.168.1.1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(50), count)
+}
+
+func TestRateLimiter_LocalCacheSharedCount(t *testing.T) {
+	store := storage.NewMockStorage()
+	config := Config{Limit: 10, Window: time.Minute, BlockTime: time.Minute}
+	first := NewRateLimiter(store, config, WithLocalCache(LocalCacheConfig{BatchSize: 2, SyncInterval: time.Minute}))
+	second := NewRateLimiter(store, config, WithLocalCache(LocalCacheConfig{BatchSize: 2, SyncInterval: time.Minute}))
+	ctx := context.Background()
+
+	allowed := 0
+	for i := 0; i < 20; i++ {
+		for _, rl := range []*RateLimiter{first, second} {
+			result, err := rl.CheckLimit(ctx, "192.168.1.1", "")
+			require.NoError(t, err)
+			if result.Allowed {
+				allowed++
+			}
+		}
+	}
+
+	// Each instance may over-admit up to BatchSize-1 hits.
+	assert.GreaterOrEqual(t, allowed, 10)
+	assert.LessOrEqual(t, allowed, 12)
+}
+
+func TestRateLimiter_LocalCacheBlocks(t *testing.T) {
+	store := &countingStorage{Storage: storage.NewMockStorage()}
+	rateLimiter := NewRateLimiter(store,
+		Config{Limit: 1, Window: time.Minute, BlockTime: time.Minute},
+		WithLocalCache(LocalCacheConfig{BatchSize: 1, SyncInterval: time.Minute}),
+	)
+	ctx := context.Background()
+
+	result, err := rateLimiter.CheckLimit(ctx, "192.168.1.1", "")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	result, err = rateLimiter.CheckLimit(ctx, "192.168.1.1", "")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+
+	calls := store.calls
+	for i := 0; i < 10; i++ {
+		result, err = rateLimiter.CheckLimit(ctx, "192.168.1.1", "")
+		require.NoError(t, err)
+		assert.False(t, result.Allowed)
+		assert.WithinDuration(t, time.Now().Add(time.Minute), result.ResetTime, time.Second)
+	}
+	assert.Equal(t, calls, store.calls)
+}
+
+func TestRateLimiter_LocalCacheSyncInterval(t *testing.T) {
+	store := storage.NewMockStorage()
+	rateLimiter := NewRateLimiter(store,
+		Config{Limit: 100, Window: time.Minute, BlockTime: time.Minute},
+		WithLocalCache(LocalCacheConfig{BatchSize: 100, SyncInterval: 10 * time.Millisecond}),
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go rateLimiter.RunLocalCache(ctx)
+
+	for i := 0; i < 5; i++ {
+		_, err := rateLimiter.CheckLimit(ctx, "192.168.1.1", "")
+		require.NoError(t, err)
+	}
+
+	assert.Eventually(t, func() bool {
+		count, err := store.Get(ctx, "ip:192.168.1.1")
+		return err == nil && count == 5
+	}, time.Second, 5*time.Millisecond)
+}
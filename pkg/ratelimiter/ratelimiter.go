@@ -0,0 +1,444 @@
+package ratelimiter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/tiago-kimura/rate-limiter/pkg/clock"
+	"github.com/tiago-kimura/rate-limiter/pkg/storage"
+)
+
+type LimitType string
+
+const (
+	IPLimit         LimitType = "ip"
+	TokenLimit      LimitType = "token"
+	HeaderLimit     LimitType = "header"
+	CookieLimit     LimitType = "cookie"
+	QueryLimit      LimitType = "query"
+	PathLimit       LimitType = "path"
+	ClientCertLimit LimitType = "cert"
+	JWTLimit        LimitType = "jwt"
+	DescriptorLimit LimitType = "descriptor"
+	KeySetLimit     LimitType = "keys"
+)
+
+var ErrNoIdentity = errors.New("no identity to apply a rate limit to")
+
+// Identity is a value a request can be limited by, such as an IP address,
+// a token or a header value. Tier optionally names the limit tier the
+// identity belongs to.
+type Identity struct {
+	Type  LimitType
+	Value string
+	Tier  string
+}
+
+func (id Identity) Key() string {
+	return fmt.Sprintf("%s:%s", id.Type, id.Value)
+}
+
+var keyPartEscaper = strings.NewReplacer(`\`, `\\`, "|", `\|`, "=", `\=`)
+
+// EscapeKeyPart escapes "|" and "=" in part, so identity values joined from
+// several parts with them, such as "a=1|b=2", cannot be forged by a part
+// containing the separators.
+func EscapeKeyPart(part string) string {
+	return keyPartEscaper.Replace(part)
+}
+
+// CombineIdentities merges identities into a single one that is limited as a
+// whole, e.g. a tenant header together with the client IP.
+func CombineIdentities(ids ...Identity) Identity {
+	types := make([]string, len(ids))
+	values := make([]string, len(ids))
+	for i, id := range ids {
+		types[i] = string(id.Type)
+		values[i] = EscapeKeyPart(id.Value)
+	}
+
+	return Identity{
+		Type:  LimitType(strings.Join(types, "+")),
+		Value: strings.Join(values, "|"),
+	}
+}
+
+type Config struct {
+	Limit     int64
+	Window    time.Duration
+	BlockTime time.Duration
+	// Priority ranks identities for load shedding; a Shedder rejects lower
+	// priorities first. Zero is the lowest.
+	Priority int
+}
+
+type RateLimiter struct {
+	storage      storage.Storage
+	ipConfig     Config
+	tokenConfigs map[string]Config
+	typeConfigs  map[LimitType]Config
+	tierConfigs  map[string]Config
+	tierResolver TierResolver
+	adaptive     *AdaptiveLimiter
+	cache        *localCache
+	clock        clock.Clock
+	audit        *slog.Logger
+
+	hashSecret         []byte
+	hashedTokenConfigs map[string]Config
+}
+
+type Option func(*RateLimiter)
+
+func WithTokenConfig(token string, config Config) Option {
+	return func(rl *RateLimiter) {
+		rl.tokenConfigs[token] = config
+	}
+}
+
+func WithTokenConfigs(configs map[string]Config) Option {
+	return func(rl *RateLimiter) {
+		for token, config := range configs {
+			rl.tokenConfigs[token] = config
+		}
+	}
+}
+
+// WithTypeConfig sets the limit applied to identities of the given type.
+// Types without a config fall back to the IP config.
+func WithTypeConfig(limitType LimitType, config Config) Option {
+	return func(rl *RateLimiter) {
+		rl.typeConfigs[limitType] = config
+	}
+}
+
+// WithTierConfig sets the limit applied to identities of the given tier,
+// taking precedence over token and type configs.
+func WithTierConfig(tier string, config Config) Option {
+	return func(rl *RateLimiter) {
+		rl.tierConfigs[tier] = config
+	}
+}
+
+// WithTierResolver maps tokens without their own config to a tier, so the
+// limits of all tokens in a tier are defined in one place.
+func WithTierResolver(resolver TierResolver) Option {
+	return func(rl *RateLimiter) {
+		rl.tierResolver = resolver
+	}
+}
+
+// WithClock makes the RateLimiter compute reset and block times from the
+// given clock instead of the system clock. Pair a clock.Fake with a
+// MockStorage using the same clock to test windows and blocks without
+// sleeping.
+func WithClock(c clock.Clock) Option {
+	return func(rl *RateLimiter) {
+		rl.clock = c
+	}
+}
+
+// Clock returns the clock reset and block times are computed from, so callers
+// can turn them into durations consistently.
+func (rl *RateLimiter) Clock() clock.Clock {
+	return rl.clock
+}
+
+func NewRateLimiter(storage storage.Storage, ipConfig Config, opts ...Option) *RateLimiter {
+	rl := &RateLimiter{
+		storage:      storage,
+		ipConfig:     ipConfig,
+		tokenConfigs: make(map[string]Config),
+		typeConfigs:  make(map[LimitType]Config),
+		tierConfigs:  make(map[string]Config),
+		clock:        clock.Real,
+
+		hashedTokenConfigs: make(map[string]Config),
+	}
+
+	for _, opt := range opts {
+		opt(rl)
+	}
+
+	if rl.cache != nil {
+		rl.cache.clock = rl.clock
+	}
+
+	rl.hashTokenConfigs()
+
+	return rl
+}
+
+func (rl *RateLimiter) SetTokenConfig(token string, config Config) {
+	if rl.hashSecret != nil {
+		token = HashToken(rl.hashSecret, token)
+	}
+	rl.tokenConfigs[token] = config
+}
+
+type CheckResult struct {
+	Allowed   bool
+	Remaining int64
+	ResetTime time.Time
+	LimitType LimitType
+	Limit     int64
+	Window    time.Duration
+	Priority  int
+
+	key  string
+	cost int64
+}
+
+func (rl *RateLimiter) CheckLimit(ctx context.Context, ip string, token string) (*CheckResult, error) {
+	return rl.Check(ctx, Identity{Type: TokenLimit, Value: token}, Identity{Type: IPLimit, Value: ip})
+}
+
+// Check applies the limit of the first identity that has one. A known tier
+// takes precedence; otherwise token identities only match when the token has
+// a config or resolves to a tier, so an unknown token falls through to the
+// next identity.
+func (rl *RateLimiter) Check(ctx context.Context, ids ...Identity) (*CheckResult, error) {
+	return rl.CheckN(ctx, 1, ids...)
+}
+
+// CheckN is like Check but counts cost units against the limit, e.g. the
+// number of items a batch job is about to process.
+func (rl *RateLimiter) CheckN(ctx context.Context, cost int64, ids ...Identity) (*CheckResult, error) {
+	id, config, err := rl.identify(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	return rl.checkIdentity(ctx, id, config, cost)
+}
+
+func (rl *RateLimiter) checkIdentity(ctx context.Context, id Identity, config Config, cost int64) (*CheckResult, error) {
+	blockLimit := config.Limit
+	if rl.adaptive != nil {
+		config.Limit = rl.adaptive.scale(config.Limit)
+	}
+
+	return rl.checkLimitForKey(ctx, id.Key(), config, blockLimit, id.Type, cost)
+}
+
+// Identify returns the identity Check applies a limit to, with its token
+// hashed when a secret is set. Limiters keyed by identity use it so that an
+// unknown token falls through to the next identity instead of getting a
+// budget of its own.
+func (rl *RateLimiter) Identify(ctx context.Context, ids ...Identity) (Identity, error) {
+	id, _, err := rl.identify(ctx, ids)
+	return id, err
+}
+
+func (rl *RateLimiter) identify(ctx context.Context, ids []Identity) (Identity, Config, error) {
+	for _, id := range ids {
+		if id.Value != "" && rl.hashSecret != nil && hashesValue(id.Type) {
+			id.Value = HashToken(rl.hashSecret, id.Value)
+		}
+
+		config, ok, err := rl.configFor(ctx, id)
+		if err != nil {
+			return Identity{}, Config{}, err
+		}
+		if ok {
+			return id, config, nil
+		}
+	}
+
+	return Identity{}, Config{}, ErrNoIdentity
+}
+
+// Refund gives back the cost an allowed check counted, e.g. when only failed
+// attempts should count toward the limit, and updates result.Remaining.
+// Results of denied checks counted nothing and are ignored, and a result is
+// refunded at most once.
+func (rl *RateLimiter) Refund(ctx context.Context, result *CheckResult) error {
+	if !result.Allowed || result.key == "" {
+		return nil
+	}
+
+	if _, _, err := rl.increment(ctx, result.key, -result.cost, result.Window); err != nil {
+		return fmt.Errorf("failed to refund counter: %w", err)
+	}
+
+	result.Remaining += result.cost
+	if result.Remaining > result.Limit {
+		result.Remaining = result.Limit
+	}
+	result.key = ""
+	return nil
+}
+
+func (rl *RateLimiter) TierConfig(tier string) (Config, bool) {
+	config, exists := rl.tierConfigs[tier]
+	return config, exists
+}
+
+func (rl *RateLimiter) configFor(ctx context.Context, id Identity) (Config, bool, error) {
+	if id.Value == "" {
+		return Config{}, false, nil
+	}
+
+	if id.Tier != "" {
+		if config, exists := rl.tierConfigs[id.Tier]; exists {
+			return config, true, nil
+		}
+	}
+
+	if id.Type == TokenLimit {
+		return rl.tokenConfig(ctx, id.Value)
+	}
+
+	if config, exists := rl.typeConfigs[id.Type]; exists {
+		return config, true, nil
+	}
+
+	return rl.ipConfig, true, nil
+}
+
+func (rl *RateLimiter) tokenConfig(ctx context.Context, token string) (Config, bool, error) {
+	if config, exists := rl.tokenConfigs[token]; exists {
+		return config, true, nil
+	}
+
+	if rl.tierResolver == nil {
+		return Config{}, false, nil
+	}
+
+	tier, ok, err := rl.tierResolver.TierForToken(ctx, token)
+	if err != nil {
+		return Config{}, false, fmt.Errorf("failed to resolve token tier: %w", err)
+	}
+	if !ok {
+		return Config{}, false, nil
+	}
+
+	config, exists := rl.tierConfigs[tier]
+	return config, exists, nil
+}
+
+// checkLimitForKey denies counts above config.Limit, and blocks the key for
+// counts above blockLimit too. The two differ when adaptive limits reduced
+// config.Limit: a client over the reduced limit only is not misbehaving, so
+// it is not blocked and its rejected requests do not count.
+func (rl *RateLimiter) checkLimitForKey(ctx context.Context, key string, config Config, blockLimit int64, limitType LimitType, cost int64) (*CheckResult, error) {
+	blockedKey := fmt.Sprintf("blocked:%s", key)
+
+	if rl.cache != nil {
+		if until, blocked := rl.cache.blockedUntil(blockedKey); blocked {
+			return deniedResult(until, limitType, config), nil
+		}
+	}
+
+	if rl.cache == nil || !rl.cache.fresh(key) {
+		blocked, err := rl.storage.Get(ctx, blockedKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check blocked status: %w", err)
+		}
+
+		if blocked > 0 {
+			ttl, err := rl.storage.TTL(ctx, blockedKey)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get block TTL: %w", err)
+			}
+
+			until := rl.clock.Now().Add(ttl)
+			if rl.cache != nil {
+				rl.cache.block(blockedKey, until)
+			}
+			return deniedResult(until, limitType, config), nil
+		}
+	}
+
+	count, resetTime, err := rl.increment(ctx, key, cost, config.Window)
+	if err != nil {
+		return nil, fmt.Errorf("failed to increment counter: %w", err)
+	}
+
+	if count > config.Limit && count <= blockLimit {
+		if _, _, err := rl.increment(ctx, key, -cost, config.Window); err != nil {
+			return nil, fmt.Errorf("failed to refund counter: %w", err)
+		}
+
+		resetTime, err = rl.resetTime(ctx, key, resetTime)
+		if err != nil {
+			return nil, err
+		}
+		return deniedResult(resetTime, limitType, config), nil
+	}
+
+	if count > config.Limit {
+		if err := rl.storage.Set(ctx, blockedKey, 1, config.BlockTime); err != nil {
+			return nil, fmt.Errorf("failed to set block: %w", err)
+		}
+
+		until := rl.clock.Now().Add(config.BlockTime)
+		if rl.cache != nil {
+			rl.cache.block(blockedKey, until)
+		}
+		rl.auditBlock(ctx, key, limitType, count, config, until)
+		return deniedResult(until, limitType, config), nil
+	}
+
+	resetTime, err = rl.resetTime(ctx, key, resetTime)
+	if err != nil {
+		return nil, err
+	}
+
+	remaining := config.Limit - count
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return &CheckResult{
+		Allowed:   true,
+		Remaining: remaining,
+		ResetTime: resetTime,
+		LimitType: limitType,
+		Limit:     config.Limit,
+		Window:    config.Window,
+		Priority:  config.Priority,
+		key:       key,
+		cost:      cost,
+	}, nil
+}
+
+// resetTime returns known, or the end of key's window when it is zero.
+func (rl *RateLimiter) resetTime(ctx context.Context, key string, known time.Time) (time.Time, error) {
+	if !known.IsZero() {
+		return known, nil
+	}
+
+	ttl, err := rl.storage.TTL(ctx, key)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get TTL: %w", err)
+	}
+	return rl.clock.Now().Add(ttl), nil
+}
+
+// increment counts cost against key, through the local cache when enabled.
+// The reset time is only known with the cache; it is zero otherwise.
+func (rl *RateLimiter) increment(ctx context.Context, key string, cost int64, window time.Duration) (int64, time.Time, error) {
+	if rl.cache != nil {
+		return rl.cache.increment(ctx, rl, key, cost, window)
+	}
+
+	count, err := rl.storage.IncrementBy(ctx, key, cost, window)
+	return count, time.Time{}, err
+}
+
+func deniedResult(resetTime time.Time, limitType LimitType, config Config) *CheckResult {
+	return &CheckResult{
+		Allowed:   false,
+		Remaining: 0,
+		ResetTime: resetTime,
+		LimitType: limitType,
+		Limit:     config.Limit,
+		Window:    config.Window,
+		Priority:  config.Priority,
+	}
+}
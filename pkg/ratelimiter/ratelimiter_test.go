@@ -0,0 +1,349 @@
+package ratelimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tiago-kimura/rate-limiter/pkg/clock"
+	"github.com/tiago-kimura/rate-limiter/pkg/storage"
+)
+
+func TestRateLimiter_IPLimiting(t *testing.T) {
+	mockStorage := storage.NewMockStorage()
+	config := Config{
+		Limit:     3,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+	}
+
+	rateLimiter := NewRateLimiter(mockStorage, config)
+	ctx := context.Background()
+	ip := "192.168.1.1"
+
+	for i := 0; i < 3; i++ {
+		result, err := rateLimiter.CheckLimit(ctx, ip, "")
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, IPLimit, result.LimitType)
+		assert.Equal(t, int64(3-i-1), result.Remaining)
+	}
+
+	result, err := rateLimiter.CheckLimit(ctx, ip, "")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, int64(0), result.Remaining)
+	assert.Equal(t, IPLimit, result.LimitType)
+
+	result, err = rateLimiter.CheckLimit(ctx, ip, "")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+}
+
+func TestRateLimiter_TokenLimiting(t *testing.T) {
+	mockStorage := storage.NewMockStorage()
+	ipConfig := Config{
+		Limit:     3,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+	}
+
+	rateLimiter := NewRateLimiter(mockStorage, ipConfig)
+
+	tokenConfig := Config{
+		Limit:     5,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+	}
+	rateLimiter.SetTokenConfig("test_token", tokenConfig)
+
+	ctx := context.Background()
+	ip := "192.168.1.1"
+	token := "test_token"
+
+	for i := 0; i < 5; i++ {
+		result, err := rateLimiter.CheckLimit(ctx, ip, token)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, TokenLimit, result.LimitType)
+		assert.Equal(t, int64(5-i-1), result.Remaining)
+	}
+
+	result, err := rateLimiter.CheckLimit(ctx, ip, token)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, int64(0), result.Remaining)
+	assert.Equal(t, TokenLimit, result.LimitType)
+}
+
+func TestRateLimiter_TokenPrecedence(t *testing.T) {
+	mockStorage := storage.NewMockStorage()
+	ipConfig := Config{
+		Limit:     2,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+	}
+
+	rateLimiter := NewRateLimiter(mockStorage, ipConfig)
+
+	tokenConfig := Config{
+		Limit:     10,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+	}
+	rateLimiter.SetTokenConfig("vip_token", tokenConfig)
+
+	ctx := context.Background()
+	ip := "192.168.1.1"
+
+	result, err := rateLimiter.CheckLimit(ctx, ip, "")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, IPLimit, result.LimitType)
+
+	result, err = rateLimiter.CheckLimit(ctx, ip, "")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, IPLimit, result.LimitType)
+
+	result, err = rateLimiter.CheckLimit(ctx, ip, "")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, IPLimit, result.LimitType)
+
+	result, err = rateLimiter.CheckLimit(ctx, "192.168.1.2", "vip_token")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, TokenLimit, result.LimitType)
+}
+
+func TestRateLimiter_UnknownToken(t *testing.T) {
+	mockStorage := storage.NewMockStorage()
+	ipConfig := Config{
+		Limit:     3,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+	}
+
+	rateLimiter := NewRateLimiter(mockStorage, ipConfig)
+	ctx := context.Background()
+	ip := "192.168.1.1"
+
+	result, err := rateLimiter.CheckLimit(ctx, ip, "unknown_token")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, IPLimit, result.LimitType)
+}
+
+func TestRateLimiter_WithTokenConfigOption(t *testing.T) {
+	mockStorage := storage.NewMockStorage()
+	ipConfig := Config{
+		Limit:     1,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+	}
+
+	rateLimiter := NewRateLimiter(mockStorage, ipConfig,
+		WithTokenConfig("opt_token", Config{Limit: 2, Window: time.Second, BlockTime: time.Minute}),
+		WithTokenConfigs(map[string]Config{
+			"other_token": {Limit: 3, Window: time.Second, BlockTime: time.Minute},
+		}),
+	)
+	ctx := context.Background()
+
+	result, err := rateLimiter.CheckLimit(ctx, "192.168.1.1", "opt_token")
+	require.NoError(t, err)
+	assert.Equal(t, TokenLimit, result.LimitType)
+	assert.Equal(t, int64(2), result.Limit)
+
+	result, err = rateLimiter.CheckLimit(ctx, "192.168.1.1", "other_token")
+	require.NoError(t, err)
+	assert.Equal(t, TokenLimit, result.LimitType)
+	assert.Equal(t, int64(3), result.Limit)
+}
+
+func TestRateLimiter_CheckIdentities(t *testing.T) {
+	mockStorage := storage.NewMockStorage()
+	ipConfig := Config{
+		Limit:     10,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+	}
+
+	rateLimiter := NewRateLimiter(mockStorage, ipConfig,
+		WithTypeConfig(HeaderLimit, Config{Limit: 1, Window: time.Second, BlockTime: time.Minute}),
+	)
+	ctx := context.Background()
+	header := Identity{Type: HeaderLimit, Value: "X-User=bob"}
+	ip := Identity{Type: IPLimit, Value: "192.168.1.1"}
+
+	result, err := rateLimiter.Check(ctx, header, ip)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, HeaderLimit, result.LimitType)
+	assert.Equal(t, int64(1), result.Limit)
+
+	result, err = rateLimiter.Check(ctx, header, ip)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+
+	result, err = rateLimiter.Check(ctx, Identity{Type: HeaderLimit}, ip)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, IPLimit, result.LimitType)
+
+	result, err = rateLimiter.Check(ctx, Identity{Type: CookieLimit, Value: "session=abc"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), result.Limit)
+
+	_, err = rateLimiter.Check(ctx, Identity{Type: TokenLimit, Value: "unknown"})
+	assert.ErrorIs(t, err, ErrNoIdentity)
+}
+
+func TestRateLimiter_CheckN(t *testing.T) {
+	mockStorage := storage.NewMockStorage()
+	config := Config{
+		Limit:     10,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+	}
+
+	rateLimiter := NewRateLimiter(mockStorage, config)
+	ctx := context.Background()
+	id := Identity{Type: KeySetLimit, Value: "job=export"}
+
+	result, err := rateLimiter.CheckN(ctx, 4, id)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, int64(6), result.Remaining)
+
+	result, err = rateLimiter.CheckN(ctx, 6, id)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, int64(0), result.Remaining)
+
+	result, err = rateLimiter.CheckN(ctx, 1, id)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+}
+
+func TestRateLimiter_Refund(t *testing.T) {
+	rateLimiter := NewRateLimiter(storage.NewMockStorage(), Config{Limit: 2, Window: time.Minute, BlockTime: time.Minute})
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		result, err := rateLimiter.CheckLimit(ctx, "192.168.1.1", "")
+		require.NoError(t, err)
+		require.True(t, result.Allowed)
+		require.NoError(t, rateLimiter.Refund(ctx, result))
+		assert.Equal(t, int64(2), result.Remaining)
+	}
+
+	// Refunding the same result twice gives back its cost once.
+	result, err := rateLimiter.CheckLimit(ctx, "192.168.1.1", "")
+	require.NoError(t, err)
+	require.NoError(t, rateLimiter.Refund(ctx, result))
+	require.NoError(t, rateLimiter.Refund(ctx, result))
+	result, err = rateLimiter.CheckLimit(ctx, "192.168.1.1", "")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), result.Remaining)
+
+	result, err = rateLimiter.CheckN(ctx, 3, Identity{Type: IPLimit, Value: "192.168.1.1"})
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.NoError(t, rateLimiter.Refund(ctx, result))
+}
+
+func newFakeClockRateLimiter(config Config, opts ...Option) (*RateLimiter, *clock.Fake) {
+	fake := clock.NewFake(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	store := storage.NewMockStorage(storage.WithMockClock(fake))
+	return NewRateLimiter(store, config, append(opts, WithClock(fake))...), fake
+}
+
+func TestRateLimiter_WindowBoundary(t *testing.T) {
+	rateLimiter, fake := newFakeClockRateLimiter(Config{Limit: 2, Window: time.Minute, BlockTime: time.Minute})
+	ctx := context.Background()
+	start := fake.Now()
+
+	result, err := rateLimiter.CheckLimit(ctx, "192.168.1.1", "")
+	require.NoError(t, err)
+	assert.Equal(t, start.Add(time.Minute), result.ResetTime)
+
+	// Hits later in the window do not move its end.
+	fake.Advance(59 * time.Second)
+	result, err = rateLimiter.CheckLimit(ctx, "192.168.1.1", "")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), result.Remaining)
+	assert.Equal(t, start.Add(time.Minute), result.ResetTime)
+
+	fake.Advance(time.Second)
+	result, err = rateLimiter.CheckLimit(ctx, "192.168.1.1", "")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, int64(1), result.Remaining)
+	assert.Equal(t, fake.Now().Add(time.Minute), result.ResetTime)
+}
+
+func TestRateLimiter_LongBlock(t *testing.T) {
+	rateLimiter, fake := newFakeClockRateLimiter(Config{Limit: 1, Window: time.Second, BlockTime: 6 * time.Hour})
+	ctx := context.Background()
+
+	_, err := rateLimiter.CheckLimit(ctx, "192.168.1.1", "")
+	require.NoError(t, err)
+
+	result, err := rateLimiter.CheckLimit(ctx, "192.168.1.1", "")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	blockedUntil := fake.Now().Add(6 * time.Hour)
+	assert.Equal(t, blockedUntil, result.ResetTime)
+
+	// The block outlasts many windows.
+	fake.Advance(5*time.Hour + 59*time.Minute)
+	result, err = rateLimiter.CheckLimit(ctx, "192.168.1.1", "")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, blockedUntil, result.ResetTime)
+
+	fake.Advance(time.Minute)
+	result, err = rateLimiter.CheckLimit(ctx, "192.168.1.1", "")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
+
+func TestRateLimiter_LongBlockWithLocalCache(t *testing.T) {
+	rateLimiter, fake := newFakeClockRateLimiter(Config{Limit: 1, Window: time.Second, BlockTime: 24 * time.Hour},
+		WithLocalCache(LocalCacheConfig{BatchSize: 1}),
+	)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		_, err := rateLimiter.CheckLimit(ctx, "192.168.1.1", "")
+		require.NoError(t, err)
+	}
+
+	fake.Advance(23 * time.Hour)
+	result, err := rateLimiter.CheckLimit(ctx, "192.168.1.1", "")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+
+	fake.Advance(time.Hour)
+	result, err = rateLimiter.CheckLimit(ctx, "192.168.1.1", "")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
+
+func TestEscapeKeyPart(t *testing.T) {
+	assert.Equal(t, "tenant-a", EscapeKeyPart("tenant-a"))
+	assert.Equal(t, `1\|b\=2`, EscapeKeyPart("1|b=2"))
+	assert.Equal(t, `a\\\|b`, EscapeKeyPart(`a\|b`))
+	assert.NotEqual(t, EscapeKeyPart(`a\`)+"|"+EscapeKeyPart("b"), EscapeKeyPart(`a\|b`))
+}
+
+func TestCombineIdentities(t *testing.T) {
+	a := CombineIdentities(Identity{Type: HeaderLimit, Value: "A=1|B=2"}, Identity{Type: HeaderLimit, Value: "B=3"})
+	b := CombineIdentities(Identity{Type: HeaderLimit, Value: "A=1"}, Identity{Type: HeaderLimit, Value: "B=2|B=3"})
+	assert.Equal(t, LimitType("header+header"), a.Type)
+	assert.NotEqual(t, a.Value, b.Value)
+}
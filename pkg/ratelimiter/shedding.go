@@ -0,0 +1,66 @@
+package ratelimiter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tiago-kimura/rate-limiter/pkg/storage"
+)
+
+const shedKey = "shed:global"
+
+// SheddingConfig sets a global capacity shared by all identities, on top of
+// their own limits.
+type SheddingConfig struct {
+	// Capacity is the number of requests admitted per Window across all
+	// identities and instances.
+	Capacity int64
+	Window   time.Duration
+	// Thresholds maps a priority to the fraction of Capacity up to which its
+	// requests are admitted, e.g. {0: 0.6, 1: 0.85} sheds priority 0 once 60%
+	// of the capacity is used. Priorities without a threshold are admitted up
+	// to the full Capacity.
+	Thresholds map[int]float64
+}
+
+// Shedder rejects requests by priority as the global capacity is consumed,
+// so low priority traffic is shed before high priority traffic once the
+// system is under load. Rejected requests do not consume capacity.
+type Shedder struct {
+	storage storage.Storage
+	config  SheddingConfig
+}
+
+func NewShedder(storage storage.Storage, config SheddingConfig) *Shedder {
+	return &Shedder{
+		storage: storage,
+		config:  config,
+	}
+}
+
+func (s *Shedder) threshold(priority int) int64 {
+	fraction, exists := s.config.Thresholds[priority]
+	if !exists || fraction > 1 {
+		fraction = 1
+	}
+	return int64(float64(s.config.Capacity) * fraction)
+}
+
+// Admit reports whether a request of the given priority fits in the capacity
+// left in the current window.
+func (s *Shedder) Admit(ctx context.Context, priority int) (bool, error) {
+	used, err := s.storage.IncrementBy(ctx, shedKey, 1, s.config.Window)
+	if err != nil {
+		return false, fmt.Errorf("failed to increment capacity usage: %w", err)
+	}
+
+	if used <= s.threshold(priority) {
+		return true, nil
+	}
+
+	if _, err := s.storage.IncrementBy(ctx, shedKey, -1, s.config.Window); err != nil {
+		return false, fmt.Errorf("failed to release capacity: %w", err)
+	}
+	return false, nil
+}
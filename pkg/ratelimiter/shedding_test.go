@@ -0,0 +1,57 @@
+package ratelimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tiago-kimura/rate-limiter/pkg/storage"
+)
+
+func TestShedder_LowerPrioritiesFirst(t *testing.T) {
+	shedder := NewShedder(storage.NewMockStorage(), SheddingConfig{
+		Capacity:   10,
+		Window:     time.Minute,
+		Thresholds: map[int]float64{0: 0.5, 1: 0.8},
+	})
+	ctx := context.Background()
+
+	admit := func(priority int) bool {
+		admitted, err := shedder.Admit(ctx, priority)
+		require.NoError(t, err)
+		return admitted
+	}
+
+	for i := 0; i < 5; i++ {
+		assert.True(t, admit(0))
+	}
+	assert.False(t, admit(0))
+
+	for i := 0; i < 3; i++ {
+		assert.True(t, admit(1))
+	}
+	assert.False(t, admit(1))
+	assert.False(t, admit(0))
+
+	assert.True(t, admit(2))
+	assert.True(t, admit(2))
+	assert.False(t, admit(2))
+}
+
+func TestRateLimiter_ResultPriority(t *testing.T) {
+	rateLimiter := NewRateLimiter(storage.NewMockStorage(),
+		Config{Limit: 10, Window: time.Minute, BlockTime: time.Minute},
+		WithTokenConfig("paid", Config{Limit: 10, Window: time.Minute, BlockTime: time.Minute, Priority: 2}),
+	)
+	ctx := context.Background()
+
+	result, err := rateLimiter.CheckLimit(ctx, "192.168.1.1", "paid")
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Priority)
+
+	result, err = rateLimiter.CheckLimit(ctx, "192.168.1.1", "")
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Priority)
+}
@@ -0,0 +1,38 @@
+package ratelimiter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// TierResolver maps a token to the tier whose limits apply to it. Implement
+// it to keep the token-to-tier mapping in an external store.
+type TierResolver interface {
+	TierForToken(ctx context.Context, token string) (tier string, ok bool, err error)
+}
+
+// StaticTiers is an in-memory token-to-tier mapping.
+type StaticTiers map[string]string
+
+func (s StaticTiers) TierForToken(ctx context.Context, token string) (string, bool, error) {
+	tier, ok := s[token]
+	return tier, ok, nil
+}
+
+// LoadTokenTiersFile reads a JSON object mapping tokens to tier names, e.g.
+// {"abc123": "pro", "vip_token": "enterprise"}.
+func LoadTokenTiersFile(path string) (StaticTiers, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token tiers file: %w", err)
+	}
+
+	tiers := StaticTiers{}
+	if err := json.Unmarshal(data, &tiers); err != nil {
+		return nil, fmt.Errorf("failed to parse token tiers file: %w", err)
+	}
+
+	return tiers, nil
+}
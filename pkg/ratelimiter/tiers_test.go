@@ -0,0 +1,87 @@
+package ratelimiter
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tiago-kimura/rate-limiter/pkg/storage"
+)
+
+type failingResolver struct{}
+
+func (failingResolver) TierForToken(ctx context.Context, token string) (string, bool, error) {
+	return "", false, errors.New("store unavailable")
+}
+
+func TestRateLimiter_TokenTiers(t *testing.T) {
+	mockStorage := storage.NewMockStorage()
+	ipConfig := Config{
+		Limit:     1,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+	}
+
+	tiers := StaticTiers{"customer_a": "pro", "customer_b": "pro", "customer_c": "missing"}
+	rateLimiter := NewRateLimiter(mockStorage, ipConfig,
+		WithTierConfig("pro", Config{Limit: 2, Window: time.Second, BlockTime: time.Minute}),
+		WithTierResolver(tiers),
+	)
+	ctx := context.Background()
+
+	for _, token := range []string{"customer_a", "customer_b"} {
+		for i := 0; i < 2; i++ {
+			result, err := rateLimiter.CheckLimit(ctx, "192.168.1.1", token)
+			require.NoError(t, err)
+			assert.True(t, result.Allowed)
+			assert.Equal(t, TokenLimit, result.LimitType)
+			assert.Equal(t, int64(2), result.Limit)
+		}
+
+		result, err := rateLimiter.CheckLimit(ctx, "192.168.1.1", token)
+		require.NoError(t, err)
+		assert.False(t, result.Allowed)
+	}
+
+	result, err := rateLimiter.CheckLimit(ctx, "192.168.1.2", "customer_c")
+	require.NoError(t, err)
+	assert.Equal(t, IPLimit, result.LimitType)
+}
+
+func TestRateLimiter_TokenConfigOverridesTier(t *testing.T) {
+	rateLimiter := NewRateLimiter(storage.NewMockStorage(), Config{Limit: 1, Window: time.Second, BlockTime: time.Minute},
+		WithTierConfig("pro", Config{Limit: 2, Window: time.Second, BlockTime: time.Minute}),
+		WithTierResolver(StaticTiers{"special": "pro"}),
+		WithTokenConfig("special", Config{Limit: 7, Window: time.Second, BlockTime: time.Minute}),
+	)
+
+	result, err := rateLimiter.CheckLimit(context.Background(), "192.168.1.1", "special")
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), result.Limit)
+}
+
+func TestRateLimiter_TierResolverError(t *testing.T) {
+	rateLimiter := NewRateLimiter(storage.NewMockStorage(), Config{Limit: 1, Window: time.Second, BlockTime: time.Minute},
+		WithTierResolver(failingResolver{}),
+	)
+
+	_, err := rateLimiter.CheckLimit(context.Background(), "192.168.1.1", "token")
+	assert.Error(t, err)
+}
+
+func TestLoadTokenTiersFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tiers.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"abc123": "pro", "vip_token": "enterprise"}`), 0o600))
+
+	tiers, err := LoadTokenTiersFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, StaticTiers{"abc123": "pro", "vip_token": "enterprise"}, tiers)
+
+	_, err = LoadTokenTiersFile(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,5 @@
+package ratelimiter
+
+// Version is the semantic version of the public rate limiter packages
+// (ratelimiter, storage and middleware). Releases are tagged as v<Version>.
+const Version = "0.1.0"
@@ -0,0 +1,78 @@
+package rls
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/tiago-kimura/rate-limiter/pkg/ratelimiter"
+)
+
+type DescriptorEntry struct {
+	Key   string `json:"key"`
+	Value string `json:"value,omitempty"`
+}
+
+// Rule maps Envoy descriptors onto a limiter tier. A descriptor matches when
+// it has the same domain and the same entry keys in the same order; an empty
+// rule value matches any value, giving every distinct value its own counter.
+type Rule struct {
+	Domain      string            `json:"domain"`
+	Descriptors []DescriptorEntry `json:"descriptors"`
+	Tier        string            `json:"tier"`
+}
+
+func (r Rule) matches(domain string, entries []DescriptorEntry) bool {
+	if r.Domain != domain || len(r.Descriptors) != len(entries) {
+		return false
+	}
+
+	for i, entry := range r.Descriptors {
+		if entry.Key != entries[i].Key {
+			return false
+		}
+		if entry.Value != "" && entry.Value != entries[i].Value {
+			return false
+		}
+	}
+
+	return true
+}
+
+// LoadRulesFile reads rules from a JSON file. Every rule must name a tier
+// rateLimiter has a config for; otherwise its descriptors would quietly get
+// the default limit.
+func LoadRulesFile(path string, rateLimiter *ratelimiter.RateLimiter) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules file: %w", err)
+	}
+
+	var rules []Rule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse rules file: %w", err)
+	}
+
+	for i, rule := range rules {
+		if rule.Domain == "" || len(rule.Descriptors) == 0 {
+			return nil, fmt.Errorf("rule %d needs a domain and at least one descriptor entry", i)
+		}
+		if _, exists := rateLimiter.TierConfig(rule.Tier); !exists {
+			return nil, fmt.Errorf("rule %d uses unknown tier %q", i, rule.Tier)
+		}
+	}
+
+	return rules, nil
+}
+
+// descriptorValue builds the identity value of a descriptor, escaped so that
+// different descriptors never share a counter.
+func descriptorValue(domain string, entries []DescriptorEntry) string {
+	parts := make([]string, 0, len(entries)+1)
+	parts = append(parts, ratelimiter.EscapeKeyPart(domain))
+	for _, entry := range entries {
+		parts = append(parts, ratelimiter.EscapeKeyPart(entry.Key)+"="+ratelimiter.EscapeKeyPart(entry.Value))
+	}
+	return strings.Join(parts, "|")
+}
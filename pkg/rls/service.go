@@ -0,0 +1,146 @@
+package rls
+
+import (
+	"context"
+	"math"
+	"time"
+
+	ratelimitv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/common/ratelimit/v3"
+	rlsv3 "github.com/envoyproxy/go-control-plane/envoy/service/ratelimit/v3"
+	"github.com/tiago-kimura/rate-limiter/pkg/ratelimiter"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// Service implements Envoy's envoy.service.ratelimit.v3.RateLimitService on
+// top of a RateLimiter. Descriptors without a matching rule are not limited.
+type Service struct {
+	rlsv3.UnimplementedRateLimitServiceServer
+
+	rateLimiter *ratelimiter.RateLimiter
+	rules       []Rule
+}
+
+func NewService(rateLimiter *ratelimiter.RateLimiter, rules []Rule) *Service {
+	return &Service{
+		rateLimiter: rateLimiter,
+		rules:       rules,
+	}
+}
+
+func (s *Service) Register(server *grpc.Server) {
+	rlsv3.RegisterRateLimitServiceServer(server, s)
+}
+
+func (s *Service) ShouldRateLimit(ctx context.Context, req *rlsv3.RateLimitRequest) (*rlsv3.RateLimitResponse, error) {
+	if req.GetDomain() == "" {
+		return nil, status.Error(codes.InvalidArgument, "domain is required")
+	}
+
+	response := &rlsv3.RateLimitResponse{OverallCode: rlsv3.RateLimitResponse_OK}
+
+	for _, descriptor := range req.GetDescriptors() {
+		descriptorStatus, err := s.check(ctx, req.GetDomain(), descriptor, hitsAddend(req, descriptor))
+		if err != nil {
+			return nil, status.Errorf(codes.Unavailable, "rate limit check failed: %v", err)
+		}
+
+		if descriptorStatus.Code == rlsv3.RateLimitResponse_OVER_LIMIT {
+			response.OverallCode = rlsv3.RateLimitResponse_OVER_LIMIT
+		}
+		response.Statuses = append(response.Statuses, descriptorStatus)
+	}
+
+	return response, nil
+}
+
+func (s *Service) check(ctx context.Context, domain string, descriptor *ratelimitv3.RateLimitDescriptor, hits int64) (*rlsv3.RateLimitResponse_DescriptorStatus, error) {
+	entries := make([]DescriptorEntry, len(descriptor.GetEntries()))
+	for i, entry := range descriptor.GetEntries() {
+		entries[i] = DescriptorEntry{Key: entry.GetKey(), Value: entry.GetValue()}
+	}
+
+	rule, ok := s.match(domain, entries)
+	if !ok {
+		return &rlsv3.RateLimitResponse_DescriptorStatus{Code: rlsv3.RateLimitResponse_OK}, nil
+	}
+
+	result, err := s.rateLimiter.CheckN(ctx, hits, ratelimiter.Identity{
+		Type:  ratelimiter.DescriptorLimit,
+		Value: descriptorValue(domain, entries),
+		Tier:  rule.Tier,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	code := rlsv3.RateLimitResponse_OK
+	if !result.Allowed {
+		code = rlsv3.RateLimitResponse_OVER_LIMIT
+	}
+
+	untilReset := result.ResetTime.Sub(s.rateLimiter.Clock().Now())
+	if untilReset < 0 {
+		untilReset = 0
+	}
+
+	return &rlsv3.RateLimitResponse_DescriptorStatus{
+		Code:               code,
+		CurrentLimit:       currentLimit(rule, result),
+		LimitRemaining:     clampUint32(result.Remaining),
+		DurationUntilReset: durationpb.New(untilReset),
+	}, nil
+}
+
+func (s *Service) match(domain string, entries []DescriptorEntry) (Rule, bool) {
+	for _, rule := range s.rules {
+		if rule.matches(domain, entries) {
+			return rule, true
+		}
+	}
+	return Rule{}, false
+}
+
+// hitsAddend returns the descriptor's hits_addend, falling back to the
+// request's; Envoy treats zero as one.
+func hitsAddend(req *rlsv3.RateLimitRequest, descriptor *ratelimitv3.RateLimitDescriptor) int64 {
+	if descriptor.GetHitsAddend() != nil {
+		return int64(descriptor.GetHitsAddend().GetValue())
+	}
+	if req.GetHitsAddend() > 0 {
+		return int64(req.GetHitsAddend())
+	}
+	return 1
+}
+
+var windowUnits = map[time.Duration]rlsv3.RateLimitResponse_RateLimit_Unit{
+	time.Second:    rlsv3.RateLimitResponse_RateLimit_SECOND,
+	time.Minute:    rlsv3.RateLimitResponse_RateLimit_MINUTE,
+	time.Hour:      rlsv3.RateLimitResponse_RateLimit_HOUR,
+	24 * time.Hour: rlsv3.RateLimitResponse_RateLimit_DAY,
+}
+
+func currentLimit(rule Rule, result *ratelimiter.CheckResult) *rlsv3.RateLimitResponse_RateLimit {
+	unit, ok := windowUnits[result.Window]
+	if !ok {
+		unit = rlsv3.RateLimitResponse_RateLimit_UNKNOWN
+	}
+
+	return &rlsv3.RateLimitResponse_RateLimit{
+		Name:            rule.Tier,
+		RequestsPerUnit: clampUint32(result.Limit),
+		Unit:            unit,
+	}
+}
+
+func clampUint32(value int64) uint32 {
+	if value < 0 {
+		return 0
+	}
+	if value > math.MaxUint32 {
+		return math.MaxUint32
+	}
+	return uint32(value)
+}
@@ -0,0 +1,221 @@
+package rls
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	ratelimitv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/common/ratelimit/v3"
+	rlsv3 "github.com/envoyproxy/go-control-plane/envoy/service/ratelimit/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tiago-kimura/rate-limiter/pkg/clock"
+	"github.com/tiago-kimura/rate-limiter/pkg/ratelimiter"
+	"github.com/tiago-kimura/rate-limiter/pkg/storage"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newRateLimiter() *ratelimiter.RateLimiter {
+	return ratelimiter.NewRateLimiter(storage.NewMockStorage(),
+		ratelimiter.Config{Limit: 100, Window: time.Second, BlockTime: time.Minute},
+		ratelimiter.WithTierConfig("per_ip", ratelimiter.Config{Limit: 2, Window: time.Minute, BlockTime: time.Minute}),
+		ratelimiter.WithTierConfig("login", ratelimiter.Config{Limit: 1, Window: time.Second, BlockTime: time.Minute}),
+	)
+}
+
+func newClient(t *testing.T, rules []Rule) rlsv3.RateLimitServiceClient {
+	return serve(t, newRateLimiter(), rules)
+}
+
+func serve(t *testing.T, rateLimiter *ratelimiter.RateLimiter, rules []Rule) rlsv3.RateLimitServiceClient {
+
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	NewService(rateLimiter, rules).Register(server)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return rlsv3.NewRateLimitServiceClient(conn)
+}
+
+func descriptor(entries ...string) *ratelimitv3.RateLimitDescriptor {
+	d := &ratelimitv3.RateLimitDescriptor{}
+	for i := 0; i+1 < len(entries); i += 2 {
+		d.Entries = append(d.Entries, &ratelimitv3.RateLimitDescriptor_Entry{Key: entries[i], Value: entries[i+1]})
+	}
+	return d
+}
+
+var testRules = []Rule{
+	{Domain: "edge", Descriptors: []DescriptorEntry{{Key: "remote_address"}}, Tier: "per_ip"},
+	{Domain: "edge", Descriptors: []DescriptorEntry{{Key: "path", Value: "/login"}}, Tier: "login"},
+}
+
+func TestService_DescriptorLimits(t *testing.T) {
+	client := newClient(t, testRules)
+	ctx := context.Background()
+
+	request := &rlsv3.RateLimitRequest{
+		Domain:      "edge",
+		Descriptors: []*ratelimitv3.RateLimitDescriptor{descriptor("remote_address", "10.0.0.1")},
+	}
+
+	for i := 0; i < 2; i++ {
+		response, err := client.ShouldRateLimit(ctx, request)
+		require.NoError(t, err)
+		assert.Equal(t, rlsv3.RateLimitResponse_OK, response.OverallCode)
+		require.Len(t, response.Statuses, 1)
+		assert.Equal(t, uint32(2-i-1), response.Statuses[0].LimitRemaining)
+		assert.Equal(t, uint32(2), response.Statuses[0].CurrentLimit.RequestsPerUnit)
+		assert.Equal(t, rlsv3.RateLimitResponse_RateLimit_MINUTE, response.Statuses[0].CurrentLimit.Unit)
+	}
+
+	response, err := client.ShouldRateLimit(ctx, request)
+	require.NoError(t, err)
+	assert.Equal(t, rlsv3.RateLimitResponse_OVER_LIMIT, response.OverallCode)
+	assert.Equal(t, rlsv3.RateLimitResponse_OVER_LIMIT, response.Statuses[0].Code)
+	assert.True(t, response.Statuses[0].DurationUntilReset.AsDuration() > 0)
+
+	response, err = client.ShouldRateLimit(ctx, &rlsv3.RateLimitRequest{
+		Domain:      "edge",
+		Descriptors: []*ratelimitv3.RateLimitDescriptor{descriptor("remote_address", "10.0.0.2")},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, rlsv3.RateLimitResponse_OK, response.OverallCode)
+}
+
+func TestService_DurationUntilResetFromLimiterClock(t *testing.T) {
+	fake := clock.NewFake(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC))
+	rateLimiter := ratelimiter.NewRateLimiter(storage.NewMockStorage(storage.WithMockClock(fake)),
+		ratelimiter.Config{Limit: 100, Window: time.Second, BlockTime: time.Minute},
+		ratelimiter.WithTierConfig("per_ip", ratelimiter.Config{Limit: 2, Window: time.Minute, BlockTime: time.Minute}),
+		ratelimiter.WithClock(fake),
+	)
+	client := serve(t, rateLimiter, testRules)
+
+	response, err := client.ShouldRateLimit(context.Background(), &rlsv3.RateLimitRequest{
+		Domain:      "edge",
+		Descriptors: []*ratelimitv3.RateLimitDescriptor{descriptor("remote_address", "10.0.0.1")},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, response.Statuses[0].DurationUntilReset.AsDuration())
+}
+
+func TestService_MultipleDescriptors(t *testing.T) {
+	client := newClient(t, testRules)
+	ctx := context.Background()
+
+	request := &rlsv3.RateLimitRequest{
+		Domain: "edge",
+		Descriptors: []*ratelimitv3.RateLimitDescriptor{
+			descriptor("remote_address", "10.0.0.1"),
+			descriptor("path", "/login"),
+			descriptor("path", "/other"),
+		},
+	}
+
+	response, err := client.ShouldRateLimit(ctx, request)
+	require.NoError(t, err)
+	assert.Equal(t, rlsv3.RateLimitResponse_OK, response.OverallCode)
+	require.Len(t, response.Statuses, 3)
+	assert.Nil(t, response.Statuses[2].CurrentLimit)
+
+	response, err = client.ShouldRateLimit(ctx, request)
+	require.NoError(t, err)
+	assert.Equal(t, rlsv3.RateLimitResponse_OVER_LIMIT, response.OverallCode)
+	assert.Equal(t, rlsv3.RateLimitResponse_OK, response.Statuses[0].Code)
+	assert.Equal(t, rlsv3.RateLimitResponse_OVER_LIMIT, response.Statuses[1].Code)
+	assert.Equal(t, rlsv3.RateLimitResponse_OK, response.Statuses[2].Code)
+}
+
+func TestService_HitsAddend(t *testing.T) {
+	client := newClient(t, testRules)
+
+	response, err := client.ShouldRateLimit(context.Background(), &rlsv3.RateLimitRequest{
+		Domain:      "edge",
+		Descriptors: []*ratelimitv3.RateLimitDescriptor{descriptor("remote_address", "10.0.0.1")},
+		HitsAddend:  2,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, rlsv3.RateLimitResponse_OK, response.OverallCode)
+	assert.Equal(t, uint32(0), response.Statuses[0].LimitRemaining)
+}
+
+func TestService_DescriptorsDoNotCollide(t *testing.T) {
+	client := newClient(t, []Rule{
+		{Domain: "edge", Descriptors: []DescriptorEntry{{Key: "user"}}, Tier: "login"},
+		{Domain: "edge", Descriptors: []DescriptorEntry{{Key: "user"}, {Key: "action"}}, Tier: "login"},
+	})
+	ctx := context.Background()
+
+	for _, d := range []*ratelimitv3.RateLimitDescriptor{
+		descriptor("user", "1|action=export"),
+		descriptor("user", "1", "action", "export"),
+	} {
+		response, err := client.ShouldRateLimit(ctx, &rlsv3.RateLimitRequest{
+			Domain:      "edge",
+			Descriptors: []*ratelimitv3.RateLimitDescriptor{d},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, rlsv3.RateLimitResponse_OK, response.OverallCode)
+	}
+}
+
+func TestService_UnknownDomainIsNotLimited(t *testing.T) {
+	client := newClient(t, testRules)
+
+	for i := 0; i < 5; i++ {
+		response, err := client.ShouldRateLimit(context.Background(), &rlsv3.RateLimitRequest{
+			Domain:      "internal",
+			Descriptors: []*ratelimitv3.RateLimitDescriptor{descriptor("remote_address", "10.0.0.1")},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, rlsv3.RateLimitResponse_OK, response.OverallCode)
+	}
+}
+
+func TestService_MissingDomain(t *testing.T) {
+	client := newClient(t, testRules)
+
+	_, err := client.ShouldRateLimit(context.Background(), &rlsv3.RateLimitRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestLoadRulesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[
+		{"domain": "edge", "descriptors": [{"key": "remote_address"}], "tier": "per_ip"}
+	]`), 0o600))
+
+	rules, err := LoadRulesFile(path, newRateLimiter())
+	require.NoError(t, err)
+	assert.Equal(t, testRules[:1], rules)
+
+	require.NoError(t, os.WriteFile(path, []byte(`[{"domain": "edge"}]`), 0o600))
+	_, err = LoadRulesFile(path, newRateLimiter())
+	assert.Error(t, err)
+
+	for _, tier := range []string{"", "missing"} {
+		require.NoError(t, os.WriteFile(path, []byte(`[
+			{"domain": "edge", "descriptors": [{"key": "remote_address"}], "tier": "`+tier+`"}
+		]`), 0o600))
+		_, err = LoadRulesFile(path, newRateLimiter())
+		assert.ErrorContains(t, err, "unknown tier", tier)
+	}
+}
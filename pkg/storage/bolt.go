@@ -0,0 +1,431 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/tiago-kimura/rate-limiter/pkg/clock"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	DefaultCompactionInterval = time.Minute
+	DefaultOpenTimeout        = 5 * time.Second
+)
+
+var (
+	countersBucket = []byte("counters")
+	leasesBucket   = []byte("leases")
+)
+
+// BoltStorage keeps counters and leases in a bbolt file, for single-node
+// deployments without Redis. Unlike MockStorage its state survives restarts,
+// so long blocks and quotas with long windows are not lost.
+//
+// Expired entries are ignored on read and deleted by a background compaction,
+// see WithCompactionInterval. A file can only be opened by one process at a
+// time.
+type BoltStorage struct {
+	db    *bolt.DB
+	clock clock.Clock
+
+	done chan struct{}
+	wg   sync.WaitGroup
+	once sync.Once
+}
+
+type boltOptions struct {
+	compactionInterval time.Duration
+	openTimeout        time.Duration
+	clock              clock.Clock
+}
+
+type BoltOption func(*boltOptions)
+
+// WithCompactionInterval sets how often expired entries are deleted from the
+// file. A non-positive interval disables the background compaction; expired
+// entries are then only deleted by Compact.
+func WithCompactionInterval(interval time.Duration) BoltOption {
+	return func(o *boltOptions) {
+		o.compactionInterval = interval
+	}
+}
+
+// WithOpenTimeout sets how long NewBoltStorage waits for the file lock held
+// by another process. Zero waits forever.
+func WithOpenTimeout(timeout time.Duration) BoltOption {
+	return func(o *boltOptions) {
+		o.openTimeout = timeout
+	}
+}
+
+// WithBoltClock expires entries by the given clock, e.g. a clock.Fake,
+// instead of the system clock.
+func WithBoltClock(c clock.Clock) BoltOption {
+	return func(o *boltOptions) {
+		o.clock = c
+	}
+}
+
+// NewBoltStorage opens or creates the bbolt file at path and starts the
+// background compaction.
+func NewBoltStorage(path string, opts ...BoltOption) (*BoltStorage, error) {
+	options := boltOptions{
+		compactionInterval: DefaultCompactionInterval,
+		openTimeout:        DefaultOpenTimeout,
+		clock:              clock.Real,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: options.openTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bolt storage: %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{countersBucket, leasesBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize bolt storage: %w", err)
+	}
+
+	b := &BoltStorage{
+		db:    db,
+		clock: options.clock,
+		done:  make(chan struct{}),
+	}
+
+	if options.compactionInterval > 0 {
+		b.wg.Add(1)
+		go b.runCompaction(options.compactionInterval)
+	}
+
+	return b, nil
+}
+
+// counter is a stored value with its expiry in Unix nanoseconds; zero means
+// it never expires.
+type counter struct {
+	value  int64
+	expiry int64
+}
+
+func decodeCounter(data []byte) counter {
+	if len(data) != 16 {
+		return counter{}
+	}
+	return counter{
+		value:  int64(binary.BigEndian.Uint64(data[:8])),
+		expiry: int64(binary.BigEndian.Uint64(data[8:])),
+	}
+}
+
+func (c counter) encode() []byte {
+	data := make([]byte, 16)
+	binary.BigEndian.PutUint64(data[:8], uint64(c.value))
+	binary.BigEndian.PutUint64(data[8:], uint64(c.expiry))
+	return data
+}
+
+func (c counter) expired(now time.Time) bool {
+	return c.expiry != 0 && c.expiry <= now.UnixNano()
+}
+
+func expiryAt(now time.Time, expiration time.Duration) int64 {
+	if expiration <= 0 {
+		return 0
+	}
+	return now.Add(expiration).UnixNano()
+}
+
+// getCounter returns the live counter stored under key, if any.
+func getCounter(tx *bolt.Tx, key string, now time.Time) (counter, bool) {
+	data := tx.Bucket(countersBucket).Get([]byte(key))
+	if data == nil {
+		return counter{}, false
+	}
+
+	c := decodeCounter(data)
+	if c.expired(now) {
+		return counter{}, false
+	}
+	return c, true
+}
+
+func (b *BoltStorage) Get(ctx context.Context, key string) (int64, error) {
+	var val int64
+	err := b.db.View(func(tx *bolt.Tx) error {
+		c, _ := getCounter(tx, key, b.clock.Now())
+		val = c.value
+		return nil
+	})
+	return val, err
+}
+
+func (b *BoltStorage) Increment(ctx context.Context, key string, expiration time.Duration) (int64, error) {
+	return b.IncrementBy(ctx, key, 1, expiration)
+}
+
+// IncrementBy adds value to the counter. The expiration only applies when the
+// counter is created; incrementing an existing counter keeps its expiry.
+func (b *BoltStorage) IncrementBy(ctx context.Context, key string, value int64, expiration time.Duration) (int64, error) {
+	var val int64
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		now := b.clock.Now()
+		c, exists := getCounter(tx, key, now)
+		if !exists {
+			c.expiry = expiryAt(now, expiration)
+		}
+		c.value += value
+		val = c.value
+
+		return tx.Bucket(countersBucket).Put([]byte(key), c.encode())
+	})
+	if err != nil {
+		return 0, err
+	}
+	return val, nil
+}
+
+func (b *BoltStorage) Set(ctx context.Context, key string, count int64, expiration time.Duration) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		c := counter{value: count, expiry: expiryAt(b.clock.Now(), expiration)}
+		return tx.Bucket(countersBucket).Put([]byte(key), c.encode())
+	})
+}
+
+// TTL returns the time left before the key expires, or zero if it does not
+// exist or never expires.
+func (b *BoltStorage) TTL(ctx context.Context, key string) (time.Duration, error) {
+	var ttl time.Duration
+	err := b.db.View(func(tx *bolt.Tx) error {
+		now := b.clock.Now()
+		c, exists := getCounter(tx, key, now)
+		if exists && c.expiry != 0 {
+			ttl = time.Duration(c.expiry - now.UnixNano())
+		}
+		return nil
+	})
+	return ttl, err
+}
+
+func (b *BoltStorage) AcquireLease(ctx context.Context, key, id string, limit int64, ttl time.Duration) (bool, int64, error) {
+	var acquired bool
+	var held int64
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		leases, err := tx.Bucket(leasesBucket).CreateBucketIfNotExists([]byte(key))
+		if err != nil {
+			return err
+		}
+
+		now := b.clock.Now()
+		held, err = deleteExpiredLeases(leases, now)
+		if err != nil {
+			return err
+		}
+		if held >= limit {
+			return nil
+		}
+
+		expiry := make([]byte, 8)
+		binary.BigEndian.PutUint64(expiry, uint64(now.Add(ttl).UnixNano()))
+		if err := leases.Put([]byte(id), expiry); err != nil {
+			return err
+		}
+
+		acquired = true
+		held++
+		return nil
+	})
+	if err != nil {
+		return false, 0, err
+	}
+	return acquired, held, nil
+}
+
+func (b *BoltStorage) RenewLease(ctx context.Context, key, id string, ttl time.Duration) (bool, error) {
+	var renewed bool
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		leases := tx.Bucket(leasesBucket).Bucket([]byte(key))
+		if leases == nil {
+			return nil
+		}
+
+		now := b.clock.Now()
+		current := leases.Get([]byte(id))
+		if current == nil || int64(binary.BigEndian.Uint64(current)) <= now.UnixNano() {
+			return nil
+		}
+
+		expiry := make([]byte, 8)
+		binary.BigEndian.PutUint64(expiry, uint64(now.Add(ttl).UnixNano()))
+		if err := leases.Put([]byte(id), expiry); err != nil {
+			return err
+		}
+
+		renewed = true
+		return nil
+	})
+	return renewed, err
+}
+
+func (b *BoltStorage) ReleaseLease(ctx context.Context, key, id string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		leases := tx.Bucket(leasesBucket).Bucket([]byte(key))
+		if leases == nil {
+			return nil
+		}
+		return leases.Delete([]byte(id))
+	})
+}
+
+// deleteExpiredLeases returns the number of leases left.
+func deleteExpiredLeases(leases *bolt.Bucket, now time.Time) (int64, error) {
+	var held int64
+	var expired [][]byte
+	err := leases.ForEach(func(id, expiry []byte) error {
+		if int64(binary.BigEndian.Uint64(expiry)) <= now.UnixNano() {
+			expired = append(expired, id)
+		} else {
+			held++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, id := range expired {
+		if err := leases.Delete(id); err != nil {
+			return 0, err
+		}
+	}
+	return held, nil
+}
+
+// DeletePrefix deletes the counters and leases whose key starts with prefix.
+func (b *BoltStorage) DeletePrefix(ctx context.Context, prefix string) (int64, error) {
+	var deleted int64
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		var keys [][]byte
+		c := tx.Bucket(countersBucket).Cursor()
+		for k, _ := c.Seek([]byte(prefix)); k != nil && bytes.HasPrefix(k, []byte(prefix)); k, _ = c.Next() {
+			keys = append(keys, k)
+		}
+		for _, k := range keys {
+			if err := tx.Bucket(countersBucket).Delete(k); err != nil {
+				return err
+			}
+		}
+
+		var leaseKeys [][]byte
+		c = tx.Bucket(leasesBucket).Cursor()
+		for k, _ := c.Seek([]byte(prefix)); k != nil && bytes.HasPrefix(k, []byte(prefix)); k, _ = c.Next() {
+			leaseKeys = append(leaseKeys, k)
+		}
+		for _, k := range leaseKeys {
+			if err := tx.Bucket(leasesBucket).DeleteBucket(k); err != nil {
+				return err
+			}
+		}
+
+		deleted = int64(len(keys) + len(leaseKeys))
+		return nil
+	})
+	return deleted, err
+}
+
+// Compact deletes expired counters and leases. It runs in the background;
+// calling it directly is only needed to reclaim space right away.
+func (b *BoltStorage) Compact() error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		now := b.clock.Now()
+
+		counters := tx.Bucket(countersBucket)
+		var expired [][]byte
+		err := counters.ForEach(func(k, v []byte) error {
+			if decodeCounter(v).expired(now) {
+				expired = append(expired, k)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range expired {
+			if err := counters.Delete(k); err != nil {
+				return err
+			}
+		}
+
+		leaseKeys := tx.Bucket(leasesBucket)
+		var keys [][]byte
+		err = leaseKeys.ForEach(func(k, _ []byte) error {
+			keys = append(keys, k)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range keys {
+			held, err := deleteExpiredLeases(leaseKeys.Bucket(k), now)
+			if err != nil {
+				return err
+			}
+			if held == 0 {
+				if err := leaseKeys.DeleteBucket(k); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func (b *BoltStorage) runCompaction(interval time.Duration) {
+	defer b.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.done:
+			return
+		case <-ticker.C:
+			if err := b.Compact(); err != nil {
+				slog.Error("bolt storage compaction failed", "error", err)
+			}
+		}
+	}
+}
+
+// Ping reports whether the file is still open.
+func (b *BoltStorage) Ping(ctx context.Context) error {
+	return b.db.View(func(tx *bolt.Tx) error {
+		return nil
+	})
+}
+
+func (b *BoltStorage) Close() error {
+	var err error
+	b.once.Do(func() {
+		close(b.done)
+		b.wg.Wait()
+		err = b.db.Close()
+	})
+	return err
+}
@@ -0,0 +1,178 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tiago-kimura/rate-limiter/pkg/clock"
+	bolt "go.etcd.io/bbolt"
+)
+
+func newTestBoltStorage(t *testing.T, path string, opts ...BoltOption) *BoltStorage {
+	store, err := NewBoltStorage(path, opts...)
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestBoltStorage_Counters(t *testing.T) {
+	store := newTestBoltStorage(t, filepath.Join(t.TempDir(), "limits.db"))
+	ctx := context.Background()
+
+	val, err := store.Get(ctx, "test")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), val)
+
+	val, err = store.Increment(ctx, "test", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), val)
+
+	val, err = store.IncrementBy(ctx, "test", -3, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(-2), val)
+
+	require.NoError(t, store.Set(ctx, "test", 10, time.Minute))
+	val, err = store.Get(ctx, "test")
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), val)
+
+	ttl, err := store.TTL(ctx, "test")
+	require.NoError(t, err)
+	assert.True(t, ttl > 0 && ttl <= time.Minute)
+
+	ttl, err = store.TTL(ctx, "missing")
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(0), ttl)
+
+	assert.NoError(t, store.Ping(ctx))
+}
+
+func TestBoltStorage_Expiration(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	store := newTestBoltStorage(t, filepath.Join(t.TempDir(), "limits.db"), WithBoltClock(fake))
+	ctx := context.Background()
+
+	_, err := store.Increment(ctx, "test", 50*time.Millisecond)
+	require.NoError(t, err)
+
+	// Incrementing keeps the expiry set when the counter was created.
+	fake.Advance(30 * time.Millisecond)
+	_, err = store.Increment(ctx, "test", 50*time.Millisecond)
+	require.NoError(t, err)
+
+	fake.Advance(30 * time.Millisecond)
+	val, err := store.Get(ctx, "test")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), val)
+
+	val, err = store.Increment(ctx, "test", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), val)
+}
+
+func TestBoltStorage_SurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "limits.db")
+	ctx := context.Background()
+
+	store, err := NewBoltStorage(path)
+	require.NoError(t, err)
+	require.NoError(t, store.Set(ctx, "blocked:ip:1.2.3.4", 1, time.Hour))
+	_, err = store.IncrementBy(ctx, "token:abc", 42, 30*24*time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, store.Close())
+
+	store = newTestBoltStorage(t, path)
+
+	val, err := store.Get(ctx, "token:abc")
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), val)
+
+	ttl, err := store.TTL(ctx, "blocked:ip:1.2.3.4")
+	require.NoError(t, err)
+	assert.True(t, ttl > 59*time.Minute && ttl <= time.Hour)
+}
+
+func TestBoltStorage_Compaction(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	store := newTestBoltStorage(t, filepath.Join(t.TempDir(), "limits.db"), WithCompactionInterval(10*time.Millisecond), WithBoltClock(fake))
+	ctx := context.Background()
+
+	require.NoError(t, store.Set(ctx, "short", 1, time.Minute))
+	require.NoError(t, store.Set(ctx, "long", 1, time.Hour))
+	_, _, err := store.AcquireLease(ctx, "inflight:short", "lease", 1, time.Minute)
+	require.NoError(t, err)
+
+	countKeys := func() int {
+		var n int
+		store.db.View(func(tx *bolt.Tx) error {
+			n = tx.Bucket(countersBucket).Stats().KeyN + tx.Bucket(leasesBucket).Stats().BucketN - 1
+			return nil
+		})
+		return n
+	}
+
+	assert.Equal(t, 3, countKeys())
+	fake.Advance(2 * time.Minute)
+	assert.Eventually(t, func() bool { return countKeys() == 1 }, time.Second, 10*time.Millisecond)
+}
+
+func TestBoltStorage_CompactionDisabled(t *testing.T) {
+	store := newTestBoltStorage(t, filepath.Join(t.TempDir(), "limits.db"), WithCompactionInterval(0))
+	ctx := context.Background()
+
+	require.NoError(t, store.Set(ctx, "test", 1, time.Minute))
+	require.NoError(t, store.Compact())
+
+	val, err := store.Get(ctx, "test")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), val)
+}
+
+func TestBoltStorage_Leases(t *testing.T) {
+	store := newTestBoltStorage(t, filepath.Join(t.TempDir(), "limits.db"))
+	ctx := context.Background()
+
+	acquired, held, err := store.AcquireLease(ctx, "inflight", "a", 2, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+	assert.Equal(t, int64(1), held)
+
+	acquired, held, err = store.AcquireLease(ctx, "inflight", "b", 2, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+	assert.Equal(t, int64(2), held)
+
+	acquired, held, err = store.AcquireLease(ctx, "inflight", "c", 2, time.Minute)
+	require.NoError(t, err)
+	assert.False(t, acquired)
+	assert.Equal(t, int64(2), held)
+
+	require.NoError(t, store.ReleaseLease(ctx, "inflight", "a"))
+
+	acquired, _, err = store.AcquireLease(ctx, "inflight", "c", 2, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+}
+
+func TestBoltStorage_DeletePrefix(t *testing.T) {
+	store := newTestBoltStorage(t, filepath.Join(t.TempDir(), "limits.db"))
+	ctx := context.Background()
+
+	app := newNamespacedStorage(t, store, "app")
+	require.NoError(t, app.Set(ctx, "ip:1.2.3.4", 1, time.Minute))
+	_, _, err := app.AcquireLease(ctx, "inflight:ip:1.2.3.4", "lease", 1, time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, store.Set(ctx, "apples", 1, time.Minute))
+
+	deleted, err := app.Purge(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+
+	val, err := store.Get(ctx, "apples")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), val)
+}
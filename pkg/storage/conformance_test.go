@@ -0,0 +1,65 @@
+package storage_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/require"
+	"github.com/tiago-kimura/rate-limiter/pkg/clock"
+	"github.com/tiago-kimura/rate-limiter/pkg/storage"
+	"github.com/tiago-kimura/rate-limiter/pkg/storage/storagetest"
+)
+
+func TestMockStorage_Conformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) (storage.Storage, func(time.Duration)) {
+		fake := clock.NewFake(time.Now())
+		return storage.NewMockStorage(storage.WithMockClock(fake)), fake.Advance
+	})
+}
+
+func TestBoltStorage_Conformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) (storage.Storage, func(time.Duration)) {
+		fake := clock.NewFake(time.Now())
+		store, err := storage.NewBoltStorage(filepath.Join(t.TempDir(), "limits.db"), storage.WithBoltClock(fake))
+		require.NoError(t, err)
+		t.Cleanup(func() { store.Close() })
+		return store, fake.Advance
+	})
+}
+
+func TestRedisStorage_Conformance(t *testing.T) {
+	storagetest.Run(t, newMiniredisStorage())
+}
+
+func TestRedisStorage_BatchedConformance(t *testing.T) {
+	storagetest.Run(t, newMiniredisStorage(storage.WithBatching(time.Millisecond, 100)))
+}
+
+func TestNamespacedStorage_Conformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) (storage.Storage, func(time.Duration)) {
+		fake := clock.NewFake(time.Now())
+		store, err := storage.NewNamespacedStorage(storage.NewMockStorage(storage.WithMockClock(fake)), "app")
+		require.NoError(t, err)
+		return store, fake.Advance
+	})
+}
+
+// newMiniredisStorage runs each test against its own miniredis. Keys in
+// miniredis only expire when it is fast-forwarded, while lease expiry is
+// computed from the wall clock, so advancing does both.
+func newMiniredisStorage(opts ...storage.RedisOption) storagetest.Factory {
+	return func(t *testing.T) (storage.Storage, func(time.Duration)) {
+		server := miniredis.RunT(t)
+
+		store, err := storage.NewRedisStorage("redis://"+server.Addr()+"/0", opts...)
+		require.NoError(t, err)
+		t.Cleanup(func() { store.Close() })
+
+		return store, func(d time.Duration) {
+			time.Sleep(d)
+			server.FastForward(d)
+		}
+	}
+}
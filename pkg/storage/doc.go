@@ -0,0 +1,5 @@
+// Package storage defines the Storage interface used by the rate limiter to
+// keep counters and block markers, and the LeaseStorage interface used to
+// limit requests in flight, together with a Redis implementation, an
+// embedded on-disk BoltStorage and an in-memory MockStorage for tests.
+package storage
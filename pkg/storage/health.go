@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrNotProbed = errors.New("storage health not probed yet")
+
+const DefaultHealthInterval = 5 * time.Second
+
+// HealthMonitor pings a Storage in the background and remembers the result,
+// so readiness checks answer without a round trip and without piling up
+// pings while the backend hangs.
+type HealthMonitor struct {
+	storage  Storage
+	interval time.Duration
+	timeout  time.Duration
+	observer func(err error, latency time.Duration)
+
+	mu      sync.RWMutex
+	lastErr error
+}
+
+type HealthOption func(*HealthMonitor)
+
+// WithHealthTimeout bounds each ping. It defaults to the probe interval.
+func WithHealthTimeout(timeout time.Duration) HealthOption {
+	return func(h *HealthMonitor) {
+		h.timeout = timeout
+	}
+}
+
+// WithHealthObserver is called with the result of every ping, e.g. to export
+// metrics.
+func WithHealthObserver(observer func(err error, latency time.Duration)) HealthOption {
+	return func(h *HealthMonitor) {
+		h.observer = observer
+	}
+}
+
+// NewHealthMonitor probes storage every interval, or DefaultHealthInterval
+// when interval is not positive.
+func NewHealthMonitor(storage Storage, interval time.Duration, opts ...HealthOption) *HealthMonitor {
+	if interval <= 0 {
+		interval = DefaultHealthInterval
+	}
+
+	h := &HealthMonitor{
+		storage:  storage,
+		interval: interval,
+		timeout:  interval,
+		lastErr:  ErrNotProbed,
+	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h
+}
+
+// Run probes the storage every interval until ctx is done.
+func (h *HealthMonitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	for {
+		h.probe(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (h *HealthMonitor) probe(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := h.storage.Ping(ctx)
+	latency := time.Since(start)
+
+	h.mu.Lock()
+	h.lastErr = err
+	h.mu.Unlock()
+
+	if h.observer != nil {
+		h.observer(err, latency)
+	}
+}
+
+// Ping returns the result of the last probe.
+func (h *HealthMonitor) Ping(ctx context.Context) error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.lastErr
+}
@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type flakyStorage struct {
+	*MockStorage
+
+	mu  sync.Mutex
+	err error
+}
+
+func (s *flakyStorage) setErr(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+func (s *flakyStorage) Ping(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+func TestHealthMonitor(t *testing.T) {
+	store := &flakyStorage{MockStorage: NewMockStorage()}
+
+	var mu sync.Mutex
+	var observed []error
+	monitor := NewHealthMonitor(store, 5*time.Millisecond, WithHealthObserver(func(err error, latency time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		observed = append(observed, err)
+	}))
+	assert.ErrorIs(t, monitor.Ping(context.Background()), ErrNotProbed)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go monitor.Run(ctx)
+
+	assert.Eventually(t, func() bool { return monitor.Ping(ctx) == nil }, time.Second, time.Millisecond)
+
+	down := errors.New("connection refused")
+	store.setErr(down)
+	assert.Eventually(t, func() bool { return errors.Is(monitor.Ping(ctx), down) }, time.Second, time.Millisecond)
+
+	store.setErr(nil)
+	assert.Eventually(t, func() bool { return monitor.Ping(ctx) == nil }, time.Second, time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Contains(t, observed, down)
+}
+
+func TestHealthMonitor_DefaultInterval(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		monitor := NewHealthMonitor(NewMockStorage(), interval)
+		assert.Equal(t, DefaultHealthInterval, monitor.interval)
+		assert.Equal(t, DefaultHealthInterval, monitor.timeout)
+	}
+}
+
+func TestNewRedisStorage_ConnectTimeout(t *testing.T) {
+	start := time.Now()
+	_, err := NewRedisStorage("redis://127.0.0.1:1/0", WithConnectTimeout(300*time.Millisecond))
+
+	assert.Error(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 300*time.Millisecond)
+	assert.Less(t, time.Since(start), 3*time.Second)
+}
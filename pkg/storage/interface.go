@@ -0,0 +1,31 @@
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+type Storage interface {
+	Get(ctx context.Context, key string) (int64, error)
+	Increment(ctx context.Context, key string, expiration time.Duration) (int64, error)
+	IncrementBy(ctx context.Context, key string, value int64, expiration time.Duration) (int64, error)
+	Set(ctx context.Context, key string, count int64, expiration time.Duration) error
+	TTL(ctx context.Context, key string) (time.Duration, error)
+	// Ping reports whether the backend is reachable.
+	Ping(ctx context.Context) error
+	Close() error
+}
+
+// LeaseStorage holds expiring leases, used to limit the number of requests in
+// flight at once. A lease that is never released expires after its TTL, so a
+// crashed instance does not hold on to its slots.
+type LeaseStorage interface {
+	// AcquireLease adds the lease id under key unless limit leases are
+	// already held. It returns whether the lease was acquired and the number
+	// of leases held afterwards.
+	AcquireLease(ctx context.Context, key, id string, limit int64, ttl time.Duration) (bool, int64, error)
+	// RenewLease moves the expiry of a held lease to ttl from now. It
+	// returns false when the lease is not held anymore.
+	RenewLease(ctx context.Context, key, id string, ttl time.Duration) (bool, error)
+	ReleaseLease(ctx context.Context, key, id string) error
+}
@@ -0,0 +1,186 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tiago-kimura/rate-limiter/pkg/clock"
+)
+
+type MockStorage struct {
+	mu     sync.Mutex
+	clock  clock.Clock
+	data   map[string]int64
+	ttl    map[string]time.Time
+	leases map[string]map[string]time.Time
+}
+
+type MockOption func(*MockStorage)
+
+// WithMockClock expires keys and leases by the given clock, e.g. a
+// clock.Fake, instead of the system clock.
+func WithMockClock(c clock.Clock) MockOption {
+	return func(m *MockStorage) {
+		m.clock = c
+	}
+}
+
+func NewMockStorage(opts ...MockOption) *MockStorage {
+	m := &MockStorage{
+		clock:  clock.Real,
+		data:   make(map[string]int64),
+		ttl:    make(map[string]time.Time),
+		leases: make(map[string]map[string]time.Time),
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m
+}
+
+func (m *MockStorage) Get(ctx context.Context, key string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if expiry, exists := m.ttl[key]; exists && !m.clock.Now().Before(expiry) {
+		delete(m.data, key)
+		delete(m.ttl, key)
+		return 0, nil
+	}
+
+	if val, exists := m.data[key]; exists {
+		return val, nil
+	}
+	return 0, nil
+}
+
+func (m *MockStorage) Increment(ctx context.Context, key string, expiration time.Duration) (int64, error) {
+	return m.IncrementBy(ctx, key, 1, expiration)
+}
+
+func (m *MockStorage) IncrementBy(ctx context.Context, key string, value int64, expiration time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if expiry, exists := m.ttl[key]; exists && !m.clock.Now().Before(expiry) {
+		delete(m.data, key)
+		delete(m.ttl, key)
+	}
+
+	val := m.data[key] + value
+	m.data[key] = val
+
+	if _, exists := m.ttl[key]; !exists {
+		m.ttl[key] = m.clock.Now().Add(expiration)
+	}
+
+	return val, nil
+}
+
+func (m *MockStorage) Set(ctx context.Context, key string, count int64, expiration time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.data[key] = count
+	m.ttl[key] = m.clock.Now().Add(expiration)
+	return nil
+}
+
+func (m *MockStorage) TTL(ctx context.Context, key string) (time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if expiry, exists := m.ttl[key]; exists {
+		remaining := expiry.Sub(m.clock.Now())
+		if remaining <= 0 {
+			delete(m.data, key)
+			delete(m.ttl, key)
+			return 0, nil
+		}
+		return remaining, nil
+	}
+	return 0, nil
+}
+
+func (m *MockStorage) AcquireLease(ctx context.Context, key, id string, limit int64, ttl time.Duration) (bool, int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.clock.Now()
+	leases := m.leases[key]
+	for leaseID, expiry := range leases {
+		if !now.Before(expiry) {
+			delete(leases, leaseID)
+		}
+	}
+
+	if int64(len(leases)) >= limit {
+		return false, int64(len(leases)), nil
+	}
+
+	if leases == nil {
+		leases = make(map[string]time.Time)
+		m.leases[key] = leases
+	}
+	leases[id] = now.Add(ttl)
+
+	return true, int64(len(leases)), nil
+}
+
+func (m *MockStorage) RenewLease(ctx context.Context, key, id string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.clock.Now()
+	expiry, exists := m.leases[key][id]
+	if !exists || !now.Before(expiry) {
+		return false, nil
+	}
+
+	m.leases[key][id] = now.Add(ttl)
+	return true, nil
+}
+
+func (m *MockStorage) ReleaseLease(ctx context.Context, key, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.leases[key], id)
+	if len(m.leases[key]) == 0 {
+		delete(m.leases, key)
+	}
+	return nil
+}
+
+func (m *MockStorage) DeletePrefix(ctx context.Context, prefix string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var deleted int64
+	for key := range m.data {
+		if strings.HasPrefix(key, prefix) {
+			delete(m.data, key)
+			delete(m.ttl, key)
+			deleted++
+		}
+	}
+	for key := range m.leases {
+		if strings.HasPrefix(key, prefix) {
+			delete(m.leases, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (m *MockStorage) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (m *MockStorage) Close() error {
+	return nil
+}
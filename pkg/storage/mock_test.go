@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tiago-kimura/rate-limiter/pkg/clock"
+)
+
+func TestMockStorage_Get(t *testing.T) {
+	storage := NewMockStorage()
+	ctx := context.Background()
+
+	val, err := storage.Get(ctx, "test")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), val)
+
+	storage.data["test"] = 5
+	val, err = storage.Get(ctx, "test")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), val)
+}
+
+func TestMockStorage_Increment(t *testing.T) {
+	storage := NewMockStorage()
+	ctx := context.Background()
+
+	val, err := storage.Increment(ctx, "test", time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), val)
+
+	val, err = storage.Increment(ctx, "test", time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), val)
+}
+
+func TestMockStorage_IncrementBy(t *testing.T) {
+	storage := NewMockStorage()
+	ctx := context.Background()
+
+	val, err := storage.IncrementBy(ctx, "test", 5, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), val)
+
+	val, err = storage.IncrementBy(ctx, "test", -2, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), val)
+}
+
+func TestMockStorage_Set(t *testing.T) {
+	storage := NewMockStorage()
+	ctx := context.Background()
+
+	err := storage.Set(ctx, "test", 10, time.Minute)
+	assert.NoError(t, err)
+
+	val, err := storage.Get(ctx, "test")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), val)
+}
+
+func TestMockStorage_TTL(t *testing.T) {
+	storage := NewMockStorage()
+	ctx := context.Background()
+
+	ttl, err := storage.TTL(ctx, "test")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), ttl)
+
+	err = storage.Set(ctx, "test", 1, time.Minute)
+	assert.NoError(t, err)
+
+	ttl, err = storage.TTL(ctx, "test")
+	assert.NoError(t, err)
+	assert.True(t, ttl > 0 && ttl <= time.Minute)
+}
+
+func TestMockStorage_Expiration(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	storage := NewMockStorage(WithMockClock(fake))
+	ctx := context.Background()
+
+	err := storage.Set(ctx, "test", 1, time.Hour)
+	require.NoError(t, err)
+
+	fake.Advance(time.Hour)
+
+	val, err := storage.Get(ctx, "test")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), val)
+}
+
+func TestMockStorage_Leases(t *testing.T) {
+	storage := NewMockStorage()
+	ctx := context.Background()
+
+	ok, count, err := storage.AcquireLease(ctx, "test", "a", 2, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, int64(1), count)
+
+	ok, count, err = storage.AcquireLease(ctx, "test", "b", 2, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, int64(2), count)
+
+	ok, count, err = storage.AcquireLease(ctx, "test", "c", 2, time.Minute)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, int64(2), count)
+
+	require.NoError(t, storage.ReleaseLease(ctx, "test", "a"))
+
+	ok, _, err = storage.AcquireLease(ctx, "test", "c", 2, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestMockStorage_LeaseExpiration(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	storage := NewMockStorage(WithMockClock(fake))
+	ctx := context.Background()
+
+	ok, _, err := storage.AcquireLease(ctx, "test", "a", 1, time.Minute)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	fake.Advance(time.Minute)
+
+	ok, count, err := storage.AcquireLease(ctx, "test", "b", 1, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, int64(1), count)
+}
@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrLeasesUnsupported = errors.New("storage does not support leases")
+	ErrPurgeUnsupported  = errors.New("storage does not support purging")
+	ErrNoNamespace       = errors.New("storage has no namespace to purge")
+	ErrInvalidNamespace  = errors.New("invalid storage namespace")
+)
+
+// reservedNamespaces are the first segments of un-namespaced keys. A
+// namespace named like one of them would share keys with a deployment that
+// has no namespace.
+var reservedNamespaces = map[string]bool{
+	"ip":         true,
+	"token":      true,
+	"header":     true,
+	"cookie":     true,
+	"query":      true,
+	"path":       true,
+	"cert":       true,
+	"jwt":        true,
+	"descriptor": true,
+	"keys":       true,
+	"blocked":    true,
+	"inflight":   true,
+	"bytes":      true,
+	"shed":       true,
+}
+
+// Purger deletes keys by prefix, so the state of one namespace can be
+// dropped without touching the rest of a shared backend.
+type Purger interface {
+	// DeletePrefix deletes every key starting with prefix and returns the
+	// number of keys deleted.
+	DeletePrefix(ctx context.Context, prefix string) (int64, error)
+}
+
+// NamespacedStorage prefixes every key with "<namespace>:" before passing it
+// on, so several deployments can share one backend without their counters,
+// blocks and leases colliding. An empty namespace leaves keys unchanged.
+type NamespacedStorage struct {
+	storage Storage
+	prefix  string
+}
+
+// NewNamespacedStorage fails with ErrInvalidNamespace if the namespace
+// contains ":", since "app" would then purge the keys of "app:v2", or is
+// named like a key type such as "ip" or "blocked".
+func NewNamespacedStorage(storage Storage, namespace string) (*NamespacedStorage, error) {
+	if err := validateNamespace(namespace); err != nil {
+		return nil, err
+	}
+
+	n := &NamespacedStorage{storage: storage}
+	if namespace != "" {
+		n.prefix = namespace + ":"
+	}
+	return n, nil
+}
+
+func validateNamespace(namespace string) error {
+	if strings.Contains(namespace, ":") {
+		return fmt.Errorf("%w: %q contains \":\"", ErrInvalidNamespace, namespace)
+	}
+	if reservedNamespaces[namespace] {
+		return fmt.Errorf("%w: %q is reserved for un-namespaced keys", ErrInvalidNamespace, namespace)
+	}
+	return nil
+}
+
+func (n *NamespacedStorage) key(key string) string {
+	return n.prefix + key
+}
+
+func (n *NamespacedStorage) Get(ctx context.Context, key string) (int64, error) {
+	return n.storage.Get(ctx, n.key(key))
+}
+
+func (n *NamespacedStorage) Increment(ctx context.Context, key string, expiration time.Duration) (int64, error) {
+	return n.storage.Increment(ctx, n.key(key), expiration)
+}
+
+func (n *NamespacedStorage) IncrementBy(ctx context.Context, key string, value int64, expiration time.Duration) (int64, error) {
+	return n.storage.IncrementBy(ctx, n.key(key), value, expiration)
+}
+
+func (n *NamespacedStorage) Set(ctx context.Context, key string, count int64, expiration time.Duration) error {
+	return n.storage.Set(ctx, n.key(key), count, expiration)
+}
+
+func (n *NamespacedStorage) TTL(ctx context.Context, key string) (time.Duration, error) {
+	return n.storage.TTL(ctx, n.key(key))
+}
+
+// AcquireLease fails with ErrLeasesUnsupported unless the wrapped storage is
+// a LeaseStorage.
+func (n *NamespacedStorage) AcquireLease(ctx context.Context, key, id string, limit int64, ttl time.Duration) (bool, int64, error) {
+	leases, ok := n.storage.(LeaseStorage)
+	if !ok {
+		return false, 0, ErrLeasesUnsupported
+	}
+	return leases.AcquireLease(ctx, n.key(key), id, limit, ttl)
+}
+
+func (n *NamespacedStorage) RenewLease(ctx context.Context, key, id string, ttl time.Duration) (bool, error) {
+	leases, ok := n.storage.(LeaseStorage)
+	if !ok {
+		return false, ErrLeasesUnsupported
+	}
+	return leases.RenewLease(ctx, n.key(key), id, ttl)
+}
+
+func (n *NamespacedStorage) ReleaseLease(ctx context.Context, key, id string) error {
+	leases, ok := n.storage.(LeaseStorage)
+	if !ok {
+		return ErrLeasesUnsupported
+	}
+	return leases.ReleaseLease(ctx, n.key(key), id)
+}
+
+// Purge deletes every key of the namespace and returns the number of keys
+// deleted. It refuses to run without a namespace, which would wipe the whole
+// backend.
+func (n *NamespacedStorage) Purge(ctx context.Context) (int64, error) {
+	if n.prefix == "" {
+		return 0, ErrNoNamespace
+	}
+
+	purger, ok := n.storage.(Purger)
+	if !ok {
+		return 0, ErrPurgeUnsupported
+	}
+	return purger.DeletePrefix(ctx, n.prefix)
+}
+
+func (n *NamespacedStorage) Ping(ctx context.Context) error {
+	return n.storage.Ping(ctx)
+}
+
+func (n *NamespacedStorage) Close() error {
+	return n.storage.Close()
+}
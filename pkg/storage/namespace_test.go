@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newNamespacedStorage(t *testing.T, store Storage, namespace string) *NamespacedStorage {
+	namespaced, err := NewNamespacedStorage(store, namespace)
+	require.NoError(t, err)
+	return namespaced
+}
+
+func TestNamespacedStorage_IsolatesNamespaces(t *testing.T) {
+	store, server := newTestRedisStorage(t)
+	ctx := context.Background()
+
+	appA := newNamespacedStorage(t, store, "app-a")
+	appB := newNamespacedStorage(t, store, "app-b")
+
+	_, err := appA.IncrementBy(ctx, "ip:1.2.3.4", 3, time.Minute)
+	require.NoError(t, err)
+	_, err = appB.Increment(ctx, "ip:1.2.3.4", time.Minute)
+	require.NoError(t, err)
+
+	assert.True(t, server.Exists("app-a:ip:1.2.3.4"))
+	assert.True(t, server.Exists("app-b:ip:1.2.3.4"))
+
+	val, err := appA.Get(ctx, "ip:1.2.3.4")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), val)
+
+	val, err = appB.Get(ctx, "ip:1.2.3.4")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), val)
+
+	acquired, _, err := appA.AcquireLease(ctx, "inflight:ip:1.2.3.4", "lease", 1, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+	assert.True(t, server.Exists("app-a:inflight:ip:1.2.3.4"))
+}
+
+func TestNamespacedStorage_Purge(t *testing.T) {
+	store, server := newTestRedisStorage(t)
+	ctx := context.Background()
+
+	tenant := newNamespacedStorage(t, store, "app*")
+	other := newNamespacedStorage(t, store, "app-b")
+
+	for _, key := range []string{"ip:1.2.3.4", "token:abc", "blocked:ip:1.2.3.4"} {
+		require.NoError(t, tenant.Set(ctx, key, 1, time.Minute))
+		require.NoError(t, other.Set(ctx, key, 1, time.Minute))
+	}
+	_, _, err := tenant.AcquireLease(ctx, "inflight:ip:1.2.3.4", "lease", 1, time.Minute)
+	require.NoError(t, err)
+
+	deleted, err := tenant.Purge(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), deleted)
+
+	// The namespace is matched literally, so "app*" does not match "app-b".
+	assert.Len(t, server.Keys(), 3)
+	val, err := other.Get(ctx, "token:abc")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), val)
+}
+
+func TestNamespacedStorage_PurgeWithoutNamespace(t *testing.T) {
+	store := NewMockStorage()
+	require.NoError(t, store.Set(context.Background(), "ip:1.2.3.4", 1, time.Minute))
+
+	_, err := newNamespacedStorage(t, store, "").Purge(context.Background())
+	assert.ErrorIs(t, err, ErrNoNamespace)
+
+	val, err := store.Get(context.Background(), "ip:1.2.3.4")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), val)
+}
+
+func TestNamespacedStorage_PurgeLeavesLongerNamespaces(t *testing.T) {
+	store, server := newTestRedisStorage(t)
+	ctx := context.Background()
+
+	app := newNamespacedStorage(t, store, "app")
+	appV2 := newNamespacedStorage(t, store, "app-v2")
+
+	require.NoError(t, app.Set(ctx, "ip:1.2.3.4", 1, time.Minute))
+	require.NoError(t, appV2.Set(ctx, "ip:1.2.3.4", 1, time.Minute))
+
+	deleted, err := app.Purge(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+	assert.Equal(t, []string{"app-v2:ip:1.2.3.4"}, server.Keys())
+
+	deleted, err = appV2.Purge(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+	assert.Empty(t, server.Keys())
+}
+
+func TestNewNamespacedStorage_RejectsInvalidNamespaces(t *testing.T) {
+	for _, namespace := range []string{"app:v2", "app:", ":", "ip", "token", "blocked", "inflight", "bytes", "shed"} {
+		_, err := NewNamespacedStorage(NewMockStorage(), namespace)
+		assert.ErrorIs(t, err, ErrInvalidNamespace, namespace)
+	}
+}
@@ -0,0 +1,239 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// acquireLeaseScript drops expired leases from the sorted set, scored by
+// expiry in milliseconds, and adds the new lease if there is room.
+var acquireLeaseScript = redis.NewScript(`
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', ARGV[1])
+local count = redis.call('ZCARD', KEYS[1])
+if count >= tonumber(ARGV[3]) then
+	return {0, count}
+end
+redis.call('ZADD', KEYS[1], ARGV[2], ARGV[4])
+redis.call('PEXPIRE', KEYS[1], ARGV[5])
+return {1, count + 1}
+`)
+
+// renewLeaseScript moves the expiry of a lease that has not expired yet.
+var renewLeaseScript = redis.NewScript(`
+local expiry = redis.call('ZSCORE', KEYS[1], ARGV[2])
+if not expiry or tonumber(expiry) <= tonumber(ARGV[1]) then
+	return 0
+end
+redis.call('ZADD', KEYS[1], ARGV[3], ARGV[2])
+if redis.call('PTTL', KEYS[1]) < tonumber(ARGV[4]) then
+	redis.call('PEXPIRE', KEYS[1], ARGV[4])
+end
+return 1
+`)
+
+// incrementScript adds to a counter and sets its expiry, in milliseconds, only
+// if it has none yet, so incrementing does not extend the window.
+var incrementScript = redis.NewScript(`
+local count = redis.call('INCRBY', KEYS[1], ARGV[1])
+if redis.call('PTTL', KEYS[1]) == -1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return count
+`)
+
+const (
+	DefaultConnectTimeout = 5 * time.Second
+
+	initialConnectBackoff = 100 * time.Millisecond
+	maxConnectBackoff     = 5 * time.Second
+
+	purgeScanCount = 1000
+)
+
+// globEscaper escapes the characters SCAN MATCH treats as patterns.
+var globEscaper = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`)
+
+type RedisStorage struct {
+	client  *redis.Client
+	batcher *redisBatcher
+}
+
+type redisOptions struct {
+	connectTimeout time.Duration
+	batchWindow    time.Duration
+	batchSize      int
+}
+
+type RedisOption func(*redisOptions)
+
+// WithConnectTimeout sets how long NewRedisStorage keeps retrying, with
+// exponential backoff, until Redis answers a ping. It lets the server start
+// before Redis is up, e.g. in docker-compose.
+func WithConnectTimeout(timeout time.Duration) RedisOption {
+	return func(o *redisOptions) {
+		o.connectTimeout = timeout
+	}
+}
+
+// NewRedisStorage connects to Redis and waits until it answers a ping. Once
+// connected, the client reconnects on its own if Redis restarts; commands
+// fail while it is down.
+func NewRedisStorage(redisURL string, opts ...RedisOption) (*RedisStorage, error) {
+	options := redisOptions{connectTimeout: DefaultConnectTimeout}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	opt, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, err
+	}
+
+	client := redis.NewClient(opt)
+
+	ctx, cancel := context.WithTimeout(context.Background(), options.connectTimeout)
+	defer cancel()
+
+	if err := waitForPing(ctx, client); err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	r := &RedisStorage{
+		client: client,
+	}
+	if options.batchWindow > 0 {
+		r.batcher = newRedisBatcher(client, options.batchWindow, options.batchSize)
+	}
+
+	return r, nil
+}
+
+func waitForPing(ctx context.Context, client *redis.Client) error {
+	backoff := initialConnectBackoff
+	for {
+		err := client.Ping(ctx).Err()
+		if err == nil {
+			return nil
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("redis not reachable: %w", err)
+		case <-timer.C:
+		}
+
+		backoff *= 2
+		if backoff > maxConnectBackoff {
+			backoff = maxConnectBackoff
+		}
+	}
+}
+
+func (r *RedisStorage) Get(ctx context.Context, key string) (int64, error) {
+	val, err := r.client.Get(ctx, key).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return val, err
+}
+
+func (r *RedisStorage) Increment(ctx context.Context, key string, expiration time.Duration) (int64, error) {
+	return r.IncrementBy(ctx, key, 1, expiration)
+}
+
+func (r *RedisStorage) IncrementBy(ctx context.Context, key string, value int64, expiration time.Duration) (int64, error) {
+	if r.batcher != nil {
+		return r.batcher.increment(ctx, key, value, expiration)
+	}
+
+	return incrementScript.Run(ctx, r.client, []string{key}, value, expiration.Milliseconds()).Int64()
+}
+
+func (r *RedisStorage) Set(ctx context.Context, key string, count int64, expiration time.Duration) error {
+	return r.client.Set(ctx, key, count, expiration).Err()
+}
+
+// TTL returns the time left before the key expires, or zero if it does not
+// exist or never expires.
+func (r *RedisStorage) TTL(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := r.client.PTTL(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	if ttl < 0 {
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+func (r *RedisStorage) AcquireLease(ctx context.Context, key, id string, limit int64, ttl time.Duration) (bool, int64, error) {
+	now := time.Now()
+	res, err := acquireLeaseScript.Run(ctx, r.client, []string{key},
+		now.UnixMilli(), now.Add(ttl).UnixMilli(), limit, id, ttl.Milliseconds()).Int64Slice()
+	if err != nil {
+		return false, 0, err
+	}
+
+	return res[0] == 1, res[1], nil
+}
+
+func (r *RedisStorage) RenewLease(ctx context.Context, key, id string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	renewed, err := renewLeaseScript.Run(ctx, r.client, []string{key},
+		now.UnixMilli(), id, now.Add(ttl).UnixMilli(), ttl.Milliseconds()).Int64()
+	if err != nil {
+		return false, err
+	}
+	return renewed == 1, nil
+}
+
+func (r *RedisStorage) ReleaseLease(ctx context.Context, key, id string) error {
+	return r.client.ZRem(ctx, key, id).Err()
+}
+
+// DeletePrefix scans for the keys starting with prefix and unlinks them page
+// by page, so it does not block Redis the way KEYS would. Keys created while
+// it runs may survive.
+func (r *RedisStorage) DeletePrefix(ctx context.Context, prefix string) (int64, error) {
+	match := globEscaper.Replace(prefix) + "*"
+
+	var deleted int64
+	var cursor uint64
+	for {
+		keys, next, err := r.client.Scan(ctx, cursor, match, purgeScanCount).Result()
+		if err != nil {
+			return deleted, err
+		}
+
+		if len(keys) > 0 {
+			n, err := r.client.Unlink(ctx, keys...).Result()
+			if err != nil {
+				return deleted, err
+			}
+			deleted += n
+		}
+
+		cursor = next
+		if cursor == 0 {
+			return deleted, nil
+		}
+	}
+}
+
+func (r *RedisStorage) Ping(ctx context.Context) error {
+	return r.client.Ping(ctx).Err()
+}
+
+func (r *RedisStorage) Close() error {
+	if r.batcher != nil {
+		r.batcher.close()
+	}
+	return r.client.Close()
+}
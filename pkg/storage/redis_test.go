@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRedisStorage(t *testing.T, opts ...RedisOption) (*RedisStorage, *miniredis.Miniredis) {
+	server := miniredis.RunT(t)
+
+	store, err := NewRedisStorage("redis://"+server.Addr()+"/0", opts...)
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+
+	return store, server
+}
+
+func TestRedisStorage_BatchedIncrements(t *testing.T) {
+	store, _ := newTestRedisStorage(t, WithBatching(5*time.Millisecond, 100))
+	ctx := context.Background()
+	before := store.client.PoolStats()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := store.IncrementBy(ctx, "counter", 2, time.Minute)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	// Every pipeline takes one connection from the pool.
+	after := store.client.PoolStats()
+	assert.Less(t, (after.Hits+after.Misses)-(before.Hits+before.Misses), uint32(10))
+
+	val, err := store.Get(ctx, "counter")
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), val)
+
+	ttl, err := store.TTL(ctx, "counter")
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, ttl)
+}
+
+// stallFirstPipeline holds the first pipeline until release is closed.
+type stallFirstPipeline struct {
+	started atomic.Bool
+	stalled chan struct{}
+	release chan struct{}
+}
+
+func (h *stallFirstPipeline) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return ctx, nil
+}
+
+func (h *stallFirstPipeline) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	return nil
+}
+
+func (h *stallFirstPipeline) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	if h.started.CompareAndSwap(false, true) {
+		close(h.stalled)
+		<-h.release
+	}
+	return ctx, nil
+}
+
+func (h *stallFirstPipeline) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	return nil
+}
+
+func TestRedisStorage_BatchesRunConcurrently(t *testing.T) {
+	store, _ := newTestRedisStorage(t, WithBatching(time.Millisecond, 10))
+	hook := &stallFirstPipeline{stalled: make(chan struct{}), release: make(chan struct{})}
+	store.client.AddHook(hook)
+	ctx := context.Background()
+
+	slow := make(chan error)
+	go func() {
+		_, err := store.IncrementBy(ctx, "slow", 1, time.Minute)
+		slow <- err
+	}()
+	<-hook.stalled
+
+	val, err := store.IncrementBy(ctx, "fast", 1, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), val)
+
+	close(hook.release)
+	assert.NoError(t, <-slow)
+}
+
+func TestRedisStorage_BatchedIncrementErrors(t *testing.T) {
+	store, server := newTestRedisStorage(t, WithBatching(time.Millisecond, 10))
+	ctx := context.Background()
+
+	server.Set("text", "not a number")
+
+	_, err := store.IncrementBy(ctx, "text", 1, time.Minute)
+	assert.Error(t, err)
+
+	val, err := store.IncrementBy(ctx, "counter", 1, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), val)
+
+	require.NoError(t, store.Close())
+	_, err = store.IncrementBy(ctx, "counter", 1, time.Minute)
+	assert.Error(t, err)
+}
@@ -0,0 +1,373 @@
+// Package storagetest is a conformance suite for storage.Storage
+// implementations. A backend passes it by running Run from its own tests:
+//
+//	func TestMyStorage(t *testing.T) {
+//		storagetest.Run(t, func(t *testing.T) (storage.Storage, func(time.Duration)) {
+//			return NewMyStorage(), nil
+//		})
+//	}
+//
+// Lease and purge semantics are checked too when the storage implements
+// storage.LeaseStorage or storage.Purger.
+package storagetest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tiago-kimura/rate-limiter/pkg/storage"
+)
+
+// Factory returns a new, empty storage for a single test and a function
+// moving its clock forward. A nil advance function sleeps instead. The suite
+// does not close the storage; use t.Cleanup for that.
+type Factory func(t *testing.T) (store storage.Storage, advance func(time.Duration))
+
+// ttl is the expiration used by tests that wait for keys to expire. It is
+// short so backends advanced by sleeping stay fast, and long enough for a
+// few round trips to fit in it.
+const ttl = 200 * time.Millisecond
+
+type suite struct {
+	store   storage.Storage
+	advance func(time.Duration)
+}
+
+func newSuite(t *testing.T, newStorage Factory) *suite {
+	store, advance := newStorage(t)
+	if advance == nil {
+		advance = time.Sleep
+	}
+	return &suite{store: store, advance: advance}
+}
+
+// Run runs the conformance suite, each test against a storage from
+// newStorage.
+func Run(t *testing.T, newStorage Factory) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, s *suite)
+	}{
+		{"GetMissing", testGetMissing},
+		{"Increment", testIncrement},
+		{"IncrementBy", testIncrementBy},
+		{"Set", testSet},
+		{"TTLMissing", testTTLMissing},
+		{"TTL", testTTL},
+		{"Expiration", testExpiration},
+		{"IncrementKeepsTTL", testIncrementKeepsTTL},
+		{"SetResetsTTL", testSetResetsTTL},
+		{"IncrementAfterExpiration", testIncrementAfterExpiration},
+		{"ConcurrentIncrements", testConcurrentIncrements},
+		{"Ping", testPing},
+		{"Leases", testLeases},
+		{"LeaseExpiration", testLeaseExpiration},
+		{"LeaseRenewal", testLeaseRenewal},
+		{"DeletePrefix", testDeletePrefix},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newSuite(t, newStorage))
+		})
+	}
+}
+
+func testGetMissing(t *testing.T, s *suite) {
+	val, err := s.store.Get(context.Background(), "missing")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), val)
+}
+
+func testIncrement(t *testing.T, s *suite) {
+	ctx := context.Background()
+
+	for want := int64(1); want <= 3; want++ {
+		val, err := s.store.Increment(ctx, "counter", time.Minute)
+		require.NoError(t, err)
+		assert.Equal(t, want, val)
+	}
+
+	val, err := s.store.Get(ctx, "counter")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), val)
+}
+
+func testIncrementBy(t *testing.T, s *suite) {
+	ctx := context.Background()
+
+	val, err := s.store.IncrementBy(ctx, "counter", 5, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), val)
+
+	// Negative values are used to refund hits.
+	val, err = s.store.IncrementBy(ctx, "counter", -7, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(-2), val)
+
+	val, err = s.store.IncrementBy(ctx, "other", 0, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), val)
+}
+
+func testSet(t *testing.T, s *suite) {
+	ctx := context.Background()
+
+	require.NoError(t, s.store.Set(ctx, "key", 10, time.Minute))
+	val, err := s.store.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), val)
+
+	require.NoError(t, s.store.Set(ctx, "key", 3, time.Minute))
+	val, err = s.store.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), val)
+
+	val, err = s.store.Increment(ctx, "key", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), val)
+}
+
+func testTTLMissing(t *testing.T, s *suite) {
+	ttl, err := s.store.TTL(context.Background(), "missing")
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(0), ttl)
+}
+
+func testTTL(t *testing.T, s *suite) {
+	ctx := context.Background()
+
+	require.NoError(t, s.store.Set(ctx, "set", 1, time.Minute))
+	remaining, err := s.store.TTL(ctx, "set")
+	require.NoError(t, err)
+	assert.True(t, remaining > 59*time.Second && remaining <= time.Minute, "TTL after Set is %v", remaining)
+
+	_, err = s.store.Increment(ctx, "incremented", time.Hour)
+	require.NoError(t, err)
+	remaining, err = s.store.TTL(ctx, "incremented")
+	require.NoError(t, err)
+	assert.True(t, remaining > 59*time.Minute && remaining <= time.Hour, "TTL after Increment is %v", remaining)
+}
+
+func testExpiration(t *testing.T, s *suite) {
+	ctx := context.Background()
+
+	require.NoError(t, s.store.Set(ctx, "set", 1, ttl))
+	_, err := s.store.IncrementBy(ctx, "incremented", 5, ttl)
+	require.NoError(t, err)
+
+	s.advance(ttl + ttl/2)
+
+	for _, key := range []string{"set", "incremented"} {
+		val, err := s.store.Get(ctx, key)
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), val, key)
+
+		remaining, err := s.store.TTL(ctx, key)
+		require.NoError(t, err)
+		assert.Equal(t, time.Duration(0), remaining, key)
+	}
+}
+
+func testIncrementKeepsTTL(t *testing.T, s *suite) {
+	ctx := context.Background()
+
+	_, err := s.store.Increment(ctx, "counter", ttl)
+	require.NoError(t, err)
+
+	// A counter counts hits in a fixed window; incrementing it must not
+	// push the window's end back.
+	s.advance(ttl * 3 / 5)
+	_, err = s.store.Increment(ctx, "counter", ttl)
+	require.NoError(t, err)
+
+	remaining, err := s.store.TTL(ctx, "counter")
+	require.NoError(t, err)
+	assert.True(t, remaining > 0 && remaining <= ttl/2, "TTL after second Increment is %v", remaining)
+
+	s.advance(ttl * 3 / 5)
+	val, err := s.store.Get(ctx, "counter")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), val)
+}
+
+func testSetResetsTTL(t *testing.T, s *suite) {
+	ctx := context.Background()
+
+	require.NoError(t, s.store.Set(ctx, "key", 1, ttl))
+	require.NoError(t, s.store.Set(ctx, "key", 2, time.Minute))
+
+	s.advance(ttl + ttl/2)
+
+	val, err := s.store.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), val)
+}
+
+func testIncrementAfterExpiration(t *testing.T, s *suite) {
+	ctx := context.Background()
+
+	_, err := s.store.IncrementBy(ctx, "counter", 5, ttl)
+	require.NoError(t, err)
+
+	s.advance(ttl + ttl/2)
+
+	val, err := s.store.Increment(ctx, "counter", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), val)
+
+	remaining, err := s.store.TTL(ctx, "counter")
+	require.NoError(t, err)
+	assert.True(t, remaining > 59*time.Second, "TTL of the new window is %v", remaining)
+}
+
+func testConcurrentIncrements(t *testing.T, s *suite) {
+	ctx := context.Background()
+	const workers, increments = 20, 25
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < increments; j++ {
+				_, err := s.store.Increment(ctx, "counter", time.Minute)
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	val, err := s.store.Get(ctx, "counter")
+	require.NoError(t, err)
+	assert.Equal(t, int64(workers*increments), val)
+}
+
+func testPing(t *testing.T, s *suite) {
+	assert.NoError(t, s.store.Ping(context.Background()))
+}
+
+func testLeases(t *testing.T, s *suite) {
+	leases, ok := s.store.(storage.LeaseStorage)
+	if !ok {
+		t.Skip("storage does not implement storage.LeaseStorage")
+	}
+	ctx := context.Background()
+
+	for i := int64(1); i <= 2; i++ {
+		acquired, held, err := leases.AcquireLease(ctx, "inflight", fmt.Sprintf("lease-%d", i), 2, time.Minute)
+		require.NoError(t, err)
+		assert.True(t, acquired)
+		assert.Equal(t, i, held)
+	}
+
+	acquired, held, err := leases.AcquireLease(ctx, "inflight", "lease-3", 2, time.Minute)
+	require.NoError(t, err)
+	assert.False(t, acquired)
+	assert.Equal(t, int64(2), held)
+
+	// Leases under other keys are counted separately.
+	acquired, held, err = leases.AcquireLease(ctx, "other", "lease-3", 2, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+	assert.Equal(t, int64(1), held)
+
+	require.NoError(t, leases.ReleaseLease(ctx, "inflight", "lease-1"))
+	require.NoError(t, leases.ReleaseLease(ctx, "inflight", "unknown"))
+	require.NoError(t, leases.ReleaseLease(ctx, "missing", "lease-1"))
+
+	acquired, held, err = leases.AcquireLease(ctx, "inflight", "lease-3", 2, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+	assert.Equal(t, int64(2), held)
+}
+
+func testLeaseExpiration(t *testing.T, s *suite) {
+	leases, ok := s.store.(storage.LeaseStorage)
+	if !ok {
+		t.Skip("storage does not implement storage.LeaseStorage")
+	}
+	ctx := context.Background()
+
+	acquired, _, err := leases.AcquireLease(ctx, "inflight", "crashed", 1, ttl)
+	require.NoError(t, err)
+	require.True(t, acquired)
+
+	s.advance(ttl + ttl/2)
+
+	acquired, held, err := leases.AcquireLease(ctx, "inflight", "next", 1, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+	assert.Equal(t, int64(1), held)
+}
+
+func testLeaseRenewal(t *testing.T, s *suite) {
+	leases, ok := s.store.(storage.LeaseStorage)
+	if !ok {
+		t.Skip("storage does not implement storage.LeaseStorage")
+	}
+	ctx := context.Background()
+
+	acquired, _, err := leases.AcquireLease(ctx, "inflight", "slow", 1, ttl)
+	require.NoError(t, err)
+	require.True(t, acquired)
+
+	// Renewing before the lease expires keeps the slot past its first TTL.
+	for i := 0; i < 3; i++ {
+		s.advance(ttl / 2)
+		renewed, err := leases.RenewLease(ctx, "inflight", "slow", ttl)
+		require.NoError(t, err)
+		require.True(t, renewed)
+	}
+
+	acquired, held, err := leases.AcquireLease(ctx, "inflight", "next", 1, ttl)
+	require.NoError(t, err)
+	assert.False(t, acquired)
+	assert.Equal(t, int64(1), held)
+
+	s.advance(ttl + ttl/2)
+
+	renewed, err := leases.RenewLease(ctx, "inflight", "slow", ttl)
+	require.NoError(t, err)
+	assert.False(t, renewed)
+
+	renewed, err = leases.RenewLease(ctx, "missing", "slow", ttl)
+	require.NoError(t, err)
+	assert.False(t, renewed)
+}
+
+func testDeletePrefix(t *testing.T, s *suite) {
+	purger, ok := s.store.(storage.Purger)
+	if !ok {
+		t.Skip("storage does not implement storage.Purger")
+	}
+	ctx := context.Background()
+
+	for _, key := range []string{"app:ip:1.2.3.4", "app:blocked:ip:1.2.3.4", "apple", "other:ip:1.2.3.4"} {
+		require.NoError(t, s.store.Set(ctx, key, 1, time.Minute))
+	}
+	if leases, ok := s.store.(storage.LeaseStorage); ok {
+		_, _, err := leases.AcquireLease(ctx, "app:inflight:ip:1.2.3.4", "lease", 1, time.Minute)
+		require.NoError(t, err)
+	}
+
+	_, err := purger.DeletePrefix(ctx, "app:")
+	require.NoError(t, err)
+
+	for key, want := range map[string]int64{"app:ip:1.2.3.4": 0, "app:blocked:ip:1.2.3.4": 0, "apple": 1, "other:ip:1.2.3.4": 1} {
+		val, err := s.store.Get(ctx, key)
+		require.NoError(t, err)
+		assert.Equal(t, want, val, key)
+	}
+
+	if leases, ok := s.store.(storage.LeaseStorage); ok {
+		acquired, _, err := leases.AcquireLease(ctx, "app:inflight:ip:1.2.3.4", "next", 1, time.Minute)
+		require.NoError(t, err)
+		assert.True(t, acquired)
+	}
+}
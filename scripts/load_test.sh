@@ -7,7 +7,7 @@ echo "======================================"
 
 # Configuration
 BASE_URL="http://localhost:8080"
-ENDPOINTS=("/health" "/api/test" "/api/data")
+ENDPOINTS=("/api/test" "/api/data")
 TOKENS=("" "abc123" "vip_token")
 
 # Colors for output
@@ -126,7 +126,7 @@ test_recovery() {
     # Exceed the limit
     echo "Exceeding rate limit..."
     for i in {1..15}; do
-        make_request "/health" ""
+        make_request "/api/test" ""
     done
     
     echo -e "\n${YELLOW}Waiting for rate limit to reset (30 seconds)...${NC}"
@@ -134,7 +134,7 @@ test_recovery() {
     
     echo "Testing after reset:"
     for i in {1..3}; do
-        make_request "/health" ""
+        make_request "/api/test" ""
         sleep 1
     done
 }
@@ -144,7 +144,7 @@ check_server() {
     echo -e "\n${BLUE}🏥 Checking Server Health${NC}"
     echo "----------------------------"
     
-    health_response=$(curl -s "$BASE_URL/health")
+    health_response=$(curl -s "$BASE_URL/readyz")
     if [ $? -eq 0 ]; then
         echo -e "${GREEN}✓ Server is running${NC}"
         echo "Response: $health_response"
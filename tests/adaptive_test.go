@@ -0,0 +1,54 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tiago-kimura/rate-limiter/pkg/clock"
+	"github.com/tiago-kimura/rate-limiter/pkg/middleware"
+	"github.com/tiago-kimura/rate-limiter/pkg/ratelimiter"
+	"github.com/tiago-kimura/rate-limiter/pkg/storage"
+)
+
+func TestRateLimiterMiddleware_AdaptiveObservesBackend(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	adaptive := ratelimiter.NewAdaptiveLimiter(ratelimiter.AdaptiveConfig{
+		ErrorRateThreshold: 0.5,
+		Interval:           time.Second,
+		MinSamples:         1,
+		Decrease:           0.5,
+	}, ratelimiter.WithAdaptiveClock(fake))
+	rateLimiter := ratelimiter.NewRateLimiter(storage.NewMockStorage(),
+		ratelimiter.Config{Limit: 100, Window: time.Minute, BlockTime: time.Minute},
+		ratelimiter.WithAdaptiveLimiter(adaptive),
+	)
+
+	handler := middleware.NewRateLimiterMiddleware(rateLimiter).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+
+	serve := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/test", nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	assert.Equal(t, "100", serve().Header().Get("X-RateLimit-Limit"))
+
+	fake.Advance(time.Second)
+	recorder := serve()
+	assert.Equal(t, http.StatusBadGateway, recorder.Code)
+	assert.Less(t, adaptive.Factor(), 1.0)
+
+	limit, err := strconv.Atoi(serve().Header().Get("X-RateLimit-Limit"))
+	require.NoError(t, err)
+	assert.Less(t, limit, 100)
+}
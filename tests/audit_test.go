@@ -0,0 +1,80 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tiago-kimura/rate-limiter/pkg/middleware"
+	"github.com/tiago-kimura/rate-limiter/pkg/ratelimiter"
+	"github.com/tiago-kimura/rate-limiter/pkg/storage"
+)
+
+func TestMiddleware_AuditRouteAndRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	rateLimiter := ratelimiter.NewRateLimiter(storage.NewMockStorage(), ratelimiter.Config{
+		Limit:     1,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+	}, ratelimiter.WithAuditLogger(slog.New(slog.NewJSONHandler(&buf, nil))))
+	var upstreamID string
+	handler := middleware.NewRateLimiterMiddleware(rateLimiter).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamID = r.Header.Get(middleware.RequestIDHeader)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/api/test", nil)
+	req.RemoteAddr = "192.168.1.1:12345"
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.NotEmpty(t, recorder.Header().Get(middleware.RequestIDHeader))
+	assert.Equal(t, recorder.Header().Get(middleware.RequestIDHeader), upstreamID)
+	assert.Empty(t, buf.String())
+
+	req = httptest.NewRequest("POST", "/api/data", nil)
+	req.RemoteAddr = "192.168.1.1:12345"
+	req.Header.Set(middleware.RequestIDHeader, "req-42")
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	assert.Empty(t, recorder.Header().Get(middleware.RequestIDHeader))
+
+	var event map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &event))
+	assert.Equal(t, "ip:192.168.1.1", event["key"])
+	assert.Equal(t, "POST /api/data", event["route"])
+	assert.Equal(t, "req-42", event["request_id"])
+}
+
+func TestMiddleware_AuditRouteTemplate(t *testing.T) {
+	var buf bytes.Buffer
+	rateLimiter := ratelimiter.NewRateLimiter(storage.NewMockStorage(), ratelimiter.Config{
+		Limit:     1,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+	}, ratelimiter.WithAuditLogger(slog.New(slog.NewJSONHandler(&buf, nil))))
+
+	router := mux.NewRouter()
+	router.Use(middleware.NewRateLimiterMiddleware(rateLimiter).Handler)
+	router.HandleFunc("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	for _, path := range []string{"/users/1", "/users/2"} {
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	var event map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &event))
+	assert.Equal(t, "GET /users/{id}", event["route"])
+}
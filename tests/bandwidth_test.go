@@ -0,0 +1,82 @@
+package tests
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tiago-kimura/rate-limiter/pkg/middleware"
+	"github.com/tiago-kimura/rate-limiter/pkg/ratelimiter"
+	"github.com/tiago-kimura/rate-limiter/pkg/storage"
+)
+
+func TestRateLimiterMiddleware_BandwidthBudget(t *testing.T) {
+	mockStorage := storage.NewMockStorage()
+	rateLimiter := ratelimiter.NewRateLimiter(mockStorage, ratelimiter.Config{Limit: 100, Window: time.Minute, BlockTime: time.Minute})
+	bandwidth := ratelimiter.NewBandwidthLimiter(mockStorage, rateLimiter, ratelimiter.BandwidthConfig{Limit: 1000, Window: time.Minute})
+
+	handler := middleware.NewRateLimiterMiddleware(rateLimiter, middleware.WithBandwidthLimiter(bandwidth)).
+		Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.Copy(io.Discard, r.Body)
+			w.Write([]byte(strings.Repeat("x", 400)))
+		}))
+
+	serve := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/export", strings.NewReader(body))
+		req.RemoteAddr = "192.168.1.1:12345"
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	recorder := serve(strings.Repeat("y", 100))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "1000", recorder.Header().Get("X-Bandwidth-Limit"))
+	assert.Equal(t, "1000", recorder.Header().Get("X-Bandwidth-Remaining"))
+
+	recorder = serve("")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "500", recorder.Header().Get("X-Bandwidth-Remaining"))
+
+	recorder = serve("")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "100", recorder.Header().Get("X-Bandwidth-Remaining"))
+
+	recorder = serve("")
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	assert.Equal(t, "0", recorder.Header().Get("X-Bandwidth-Remaining"))
+	assert.Contains(t, recorder.Body.String(), "bandwidth_limit_exceeded")
+}
+
+func TestRateLimiterMiddleware_BandwidthThrottle(t *testing.T) {
+	mockStorage := storage.NewMockStorage()
+	rateLimiter := ratelimiter.NewRateLimiter(mockStorage, ratelimiter.Config{Limit: 100, Window: time.Minute, BlockTime: time.Minute})
+	bandwidth := ratelimiter.NewBandwidthLimiter(mockStorage, rateLimiter, ratelimiter.BandwidthConfig{
+		Window:         time.Minute,
+		BytesPerSecond: 20000,
+	})
+
+	handler := middleware.NewRateLimiterMiddleware(rateLimiter, middleware.WithBandwidthLimiter(bandwidth)).
+		Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for i := 0; i < 4; i++ {
+				w.Write([]byte(strings.Repeat("x", 1000)))
+			}
+		}))
+
+	req := httptest.NewRequest("GET", "/export", nil)
+	req.RemoteAddr = "192.168.1.1:12345"
+	recorder := httptest.NewRecorder()
+
+	start := time.Now()
+	handler.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, 4000, recorder.Body.Len())
+	assert.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+	assert.Empty(t, recorder.Header().Get("X-Bandwidth-Limit"))
+}
@@ -0,0 +1,159 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tiago-kimura/rate-limiter/pkg/metrics"
+	"github.com/tiago-kimura/rate-limiter/pkg/middleware"
+	"github.com/tiago-kimura/rate-limiter/pkg/ratelimiter"
+	"github.com/tiago-kimura/rate-limiter/pkg/storage"
+)
+
+func newConcurrencyLimiter(leaseTTL time.Duration) *ratelimiter.ConcurrencyLimiter {
+	store := storage.NewMockStorage()
+	rateLimiter := ratelimiter.NewRateLimiter(store, ratelimiter.Config{Limit: 100, Window: time.Second, BlockTime: time.Minute})
+	return ratelimiter.NewConcurrencyLimiter(store, rateLimiter, ratelimiter.ConcurrencyConfig{
+		Limit:    1,
+		LeaseTTL: leaseTTL,
+	})
+}
+
+func TestConcurrencyMiddleware_LimitsInFlight(t *testing.T) {
+	limiter := newConcurrencyLimiter(time.Minute)
+	registry := prometheus.NewRegistry()
+	mw := middleware.NewConcurrencyMiddleware(limiter, middleware.WithConcurrencyMetrics(metrics.NewConcurrency(registry)))
+
+	started := make(chan struct{})
+	finish := make(chan struct{})
+	handler := mw.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/report" {
+			close(started)
+			<-finish
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- serve("/report") }()
+	<-started
+
+	recorder := serve("/other")
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	assert.Equal(t, "1", recorder.Header().Get("X-Concurrency-Limit"))
+	assert.Equal(t, "0", recorder.Header().Get("X-Concurrency-Remaining"))
+	assert.Contains(t, recorder.Body.String(), "concurrency_limit_exceeded")
+
+	close(finish)
+	recorder = <-done
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "0", recorder.Header().Get("X-Concurrency-Remaining"))
+
+	assert.Equal(t, http.StatusOK, serve("/other").Code)
+
+	count, err := testutil.GatherAndCount(registry, "ratelimiter_concurrency_rejected_total")
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, 0.0, gaugeValue(t, registry, "ratelimiter_concurrency_in_flight"))
+}
+
+// serveInBackground serves req with a handler that blocks until the returned
+// function is called, once the request holds its slot.
+func serveInBackground(mw *middleware.ConcurrencyMiddleware, req *http.Request) (finish func() *httptest.ResponseRecorder) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan *httptest.ResponseRecorder)
+
+	handler := mw.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	go func() {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		done <- recorder
+	}()
+	<-started
+
+	return func() *httptest.ResponseRecorder {
+		close(release)
+		return <-done
+	}
+}
+
+func serveConcurrency(mw *middleware.ConcurrencyMiddleware, req *http.Request) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	mw.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})).ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestConcurrencyMiddleware_RandomAPIKeysShareIPSlot(t *testing.T) {
+	mw := middleware.NewConcurrencyMiddleware(newConcurrencyLimiter(time.Minute))
+
+	request := func(apiKey string) *http.Request {
+		req := httptest.NewRequest("GET", "/report", nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+		req.Header.Set("API_KEY", apiKey)
+		return req
+	}
+
+	finish := serveInBackground(mw, request("random-1"))
+
+	assert.Equal(t, http.StatusTooManyRequests, serveConcurrency(mw, request("random-2")).Code)
+	assert.Equal(t, http.StatusOK, finish().Code)
+}
+
+func TestConcurrencyMiddleware_RenewsLongRequests(t *testing.T) {
+	const leaseTTL = 60 * time.Millisecond
+	mw := middleware.NewConcurrencyMiddleware(newConcurrencyLimiter(leaseTTL))
+
+	request := func() *http.Request {
+		req := httptest.NewRequest("GET", "/report", nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+		return req
+	}
+
+	finish := serveInBackground(mw, request())
+
+	// The slow request outlives its lease TTL several times over.
+	for i := 0; i < 4; i++ {
+		time.Sleep(leaseTTL)
+		assert.Equal(t, http.StatusTooManyRequests, serveConcurrency(mw, request()).Code)
+	}
+
+	assert.Equal(t, http.StatusOK, finish().Code)
+	assert.Equal(t, http.StatusOK, serveConcurrency(mw, request()).Code)
+}
+
+func gaugeValue(t *testing.T, registry *prometheus.Registry, name string) float64 {
+	families, err := registry.Gather()
+	require.NoError(t, err)
+
+	for _, family := range families {
+		if family.GetName() == name {
+			return family.GetMetric()[0].GetGauge().GetValue()
+		}
+	}
+
+	t.Fatalf("metric %s not found", name)
+	return 0
+}
@@ -0,0 +1,47 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tiago-kimura/rate-limiter/pkg/middleware"
+	"github.com/tiago-kimura/rate-limiter/pkg/ratelimiter"
+	"github.com/tiago-kimura/rate-limiter/pkg/storage"
+)
+
+func TestRateLimiterMiddleware_CountsOnlyFailedLogins(t *testing.T) {
+	rateLimiter := ratelimiter.NewRateLimiter(storage.NewMockStorage(), ratelimiter.Config{
+		Limit:     2,
+		Window:    time.Minute,
+		BlockTime: time.Minute,
+	})
+	handler := middleware.NewRateLimiterMiddleware(rateLimiter, middleware.WithCountedStatuses(http.StatusUnauthorized)).
+		Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.FormValue("password") != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+
+	login := func(password string) int {
+		req := httptest.NewRequest("POST", "/login?password="+password, nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, http.StatusOK, login("secret"))
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, login("guess1"))
+	assert.Equal(t, http.StatusUnauthorized, login("guess2"))
+	assert.Equal(t, http.StatusTooManyRequests, login("guess3"))
+	assert.Equal(t, http.StatusTooManyRequests, login("secret"))
+}
@@ -0,0 +1,96 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tiago-kimura/rate-limiter/pkg/middleware"
+	"github.com/tiago-kimura/rate-limiter/pkg/ratelimiter"
+	"github.com/tiago-kimura/rate-limiter/pkg/storage"
+)
+
+func TestDecisionHandler_ForwardedIP(t *testing.T) {
+	rateLimiter := ratelimiter.NewRateLimiter(storage.NewMockStorage(), ratelimiter.Config{
+		Limit:     2,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+	})
+	handler := middleware.NewRateLimiterMiddleware(rateLimiter).DecisionHandler(http.StatusTooManyRequests)
+
+	decide := func(clientIP string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/ratelimit/decision", nil)
+		req.RemoteAddr = "10.0.0.1:12345"
+		req.Header.Set("X-Forwarded-For", clientIP)
+		req.Header.Set("X-Original-URI", "/api/test")
+		req.Header.Set("X-Original-Method", "POST")
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	for i := 0; i < 2; i++ {
+		recorder := decide("203.0.113.1")
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "2", recorder.Header().Get("X-RateLimit-Limit"))
+		assert.Equal(t, "ip", recorder.Header().Get("X-RateLimit-Type"))
+	}
+
+	recorder := decide("203.0.113.1")
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	assert.Equal(t, "0", recorder.Header().Get("X-RateLimit-Remaining"))
+
+	assert.Equal(t, http.StatusOK, decide("203.0.113.2").Code)
+}
+
+func TestDecisionHandler_APIKeyAndDeniedStatus(t *testing.T) {
+	rateLimiter := ratelimiter.NewRateLimiter(storage.NewMockStorage(),
+		ratelimiter.Config{Limit: 10, Window: time.Second, BlockTime: time.Minute},
+		ratelimiter.WithTokenConfig("abc123", ratelimiter.Config{Limit: 1, Window: time.Second, BlockTime: time.Minute}),
+	)
+	handler := middleware.NewRateLimiterMiddleware(rateLimiter).DecisionHandler(http.StatusForbidden)
+
+	decide := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/ratelimit/decision", nil)
+		req.Header.Set("X-Forwarded-For", "203.0.113.1")
+		req.Header.Set("API_KEY", "abc123")
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	recorder := decide()
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "token", recorder.Header().Get("X-RateLimit-Type"))
+
+	assert.Equal(t, http.StatusForbidden, decide().Code)
+}
+
+func TestDecisionHandler_OriginalURIDrivesExtractors(t *testing.T) {
+	rateLimiter := ratelimiter.NewRateLimiter(storage.NewMockStorage(), ratelimiter.Config{
+		Limit:     1,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+	})
+	handler := middleware.NewRateLimiterMiddleware(rateLimiter,
+		middleware.WithKeyExtractor(middleware.Query("user")),
+	).DecisionHandler(http.StatusTooManyRequests)
+
+	decide := func(uri string) int {
+		req := httptest.NewRequest("GET", "/ratelimit/decision", nil)
+		req.Header.Set("X-Forwarded-Uri", uri)
+		req.Header.Set("X-Forwarded-Method", "GET")
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	assert.Equal(t, http.StatusOK, decide("/reports?user=alice"))
+	assert.Equal(t, http.StatusTooManyRequests, decide("/reports?user=alice"))
+	assert.Equal(t, http.StatusOK, decide("/reports?user=bob"))
+}
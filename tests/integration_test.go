@@ -1,19 +1,23 @@
 package tests
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/netip"
 	"testing"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
-	"github.com/tiago-kimura/rate-limiter/internal/middleware"
-	"github.com/tiago-kimura/rate-limiter/internal/ratelimiter"
-	"github.com/tiago-kimura/rate-limiter/internal/storage"
+	"github.com/tiago-kimura/rate-limiter/pkg/middleware"
+	"github.com/tiago-kimura/rate-limiter/pkg/ratelimiter"
+	"github.com/tiago-kimura/rate-limiter/pkg/storage"
 )
 
 func TestRateLimiterMiddleware_IPLimiting(t *testing.T) {
@@ -191,3 +195,215 @@ func TestRateLimiterMiddleware_XForwardedFor(t *testing.T) {
 
 	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
 }
+
+func TestTrustedClientIP(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+
+	for _, tc := range []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		realIP     string
+		expected   string
+	}{
+		{"untrusted peer", "203.0.113.9:1234", "198.51.100.1", "198.51.100.2", "203.0.113.9"},
+		{"trusted peer", "10.0.0.1:1234", "198.51.100.1", "", "198.51.100.1"},
+		{"spoofed entry before the client", "10.0.0.1:1234", "192.0.2.66, 198.51.100.1, 10.0.0.2", "", "198.51.100.1"},
+		{"only trusted entries", "10.0.0.1:1234", "10.0.0.3, 10.0.0.2", "", "10.0.0.3"},
+		{"real IP", "10.0.0.1:1234", "", "198.51.100.2", "198.51.100.2"},
+		{"no headers", "10.0.0.1:1234", "", "", "10.0.0.1"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.RemoteAddr = tc.remoteAddr
+			if tc.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tc.forwarded)
+			}
+			if tc.realIP != "" {
+				req.Header.Set("X-Real-IP", tc.realIP)
+			}
+
+			assert.Equal(t, tc.expected, middleware.TrustedClientIP(req, trusted...))
+		})
+	}
+}
+
+func TestRateLimiterMiddleware_TrustedIPIgnoresSpoofedForwardedFor(t *testing.T) {
+	rateLimiter := ratelimiter.NewRateLimiter(storage.NewMockStorage(), ratelimiter.Config{
+		Limit:     2,
+		Window:    time.Minute,
+		BlockTime: time.Minute,
+	})
+	mw := middleware.NewRateLimiterMiddleware(rateLimiter, middleware.WithKeyExtractor(middleware.TrustedIP()))
+
+	handler := mw.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	var codes []int
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.RemoteAddr = "203.0.113.9:1234"
+		req.Header.Set("X-Forwarded-For", fmt.Sprintf("198.51.100.%d", i))
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		codes = append(codes, recorder.Code)
+	}
+
+	assert.Equal(t, []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests}, codes)
+}
+
+func TestRateLimiterMiddleware_CustomKeyExtractor(t *testing.T) {
+	mockStorage := storage.NewMockStorage()
+	config := ratelimiter.Config{
+		Limit:     1,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+	}
+
+	rateLimiter := ratelimiter.NewRateLimiter(mockStorage, config)
+	middleware := middleware.NewRateLimiterMiddleware(rateLimiter,
+		middleware.WithKeyExtractor(middleware.Header("X-Client-ID")),
+	)
+
+	router := mux.NewRouter()
+	router.Use(middleware.Handler)
+	router.HandleFunc("/test", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}).Methods("GET")
+
+	for _, clientID := range []string{"client-a", "client-b"} {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+		req.Header.Set("X-Client-ID", clientID)
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusOK, recorder.Code, "client %s should be allowed", clientID)
+	}
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.RemoteAddr = "192.168.1.1:12345"
+	req.Header.Set("X-Client-ID", "client-a")
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+}
+
+func TestRateLimiterMiddleware_NoIdentity(t *testing.T) {
+	rateLimiter := ratelimiter.NewRateLimiter(storage.NewMockStorage(), ratelimiter.Config{
+		Limit:     1,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+	})
+	middleware := middleware.NewRateLimiterMiddleware(rateLimiter,
+		middleware.WithKeyExtractor(middleware.Header("X-Client-ID")),
+	)
+
+	router := mux.NewRouter()
+	router.Use(middleware.Handler)
+	router.HandleFunc("/test", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}).Methods("GET")
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "missing_identity")
+}
+
+func TestRateLimiterMiddleware_CombinedPathVarAndQuery(t *testing.T) {
+	mockStorage := storage.NewMockStorage()
+	ipConfig := ratelimiter.Config{
+		Limit:     100,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+	}
+
+	rateLimiter := ratelimiter.NewRateLimiter(mockStorage, ipConfig,
+		ratelimiter.WithTypeConfig("path+query", ratelimiter.Config{Limit: 1, Window: time.Second, BlockTime: time.Minute}),
+	)
+	middleware := middleware.NewRateLimiterMiddleware(rateLimiter,
+		middleware.WithKeyExtractor(middleware.FirstOf(
+			middleware.Combine(middleware.PathVar("tenant"), middleware.Query("user")),
+			middleware.IP(),
+		)),
+	)
+
+	router := mux.NewRouter()
+	router.Use(middleware.Handler)
+	router.HandleFunc("/tenants/{tenant}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}).Methods("GET")
+
+	serve := func(target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", target, nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	recorder := serve("/tenants/acme?user=bob")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "path+query", recorder.Header().Get("X-RateLimit-Type"))
+
+	assert.Equal(t, http.StatusTooManyRequests, serve("/tenants/acme?user=bob").Code)
+	assert.Equal(t, http.StatusOK, serve("/tenants/acme?user=alice").Code)
+	assert.Equal(t, http.StatusOK, serve("/tenants/globex?user=bob").Code)
+
+	recorder = serve("/tenants/acme")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "ip", recorder.Header().Get("X-RateLimit-Type"))
+}
+
+func TestRateLimiterMiddleware_CookieAndClientCert(t *testing.T) {
+	mockStorage := storage.NewMockStorage()
+	config := ratelimiter.Config{
+		Limit:     1,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+	}
+
+	rateLimiter := ratelimiter.NewRateLimiter(mockStorage, config)
+	middleware := middleware.NewRateLimiterMiddleware(rateLimiter,
+		middleware.WithKeyExtractor(middleware.FirstOf(
+			middleware.ClientCertSubject(),
+			middleware.Cookie("session"),
+		)),
+	)
+
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	certRequest := func() *http.Request {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{
+			{Subject: pkix.Name{CommonName: "billing-service"}},
+		}}
+		return req
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, certRequest())
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "cert", recorder.Header().Get("X-RateLimit-Type"))
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, certRequest())
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: "s1"})
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "cookie", recorder.Header().Get("X-RateLimit-Type"))
+}
@@ -0,0 +1,175 @@
+package tests
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tiago-kimura/rate-limiter/pkg/clock"
+	"github.com/tiago-kimura/rate-limiter/pkg/middleware"
+	"github.com/tiago-kimura/rate-limiter/pkg/ratelimiter"
+	"github.com/tiago-kimura/rate-limiter/pkg/storage"
+)
+
+type jwtKeys struct {
+	hmac []byte
+	rsa  *rsa.PrivateKey
+	ec   *ecdsa.PrivateKey
+	jwks *middleware.JWKS
+}
+
+func newJWTKeys(t *testing.T) *jwtKeys {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	hmacKey := []byte("0123456789abcdef0123456789abcdef")
+
+	set := jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+		{Key: hmacKey, KeyID: "hmac", Algorithm: string(jose.HS256)},
+		{Key: rsaKey.Public(), KeyID: "rsa", Algorithm: string(jose.RS256)},
+		{Key: ecKey.Public(), KeyID: "ec", Algorithm: string(jose.ES256)},
+	}}
+	data, err := json.Marshal(set)
+	require.NoError(t, err)
+
+	jwks, err := middleware.ParseJWKS(data)
+	require.NoError(t, err)
+
+	return &jwtKeys{hmac: hmacKey, rsa: rsaKey, ec: ecKey, jwks: jwks}
+}
+
+func signJWT(t *testing.T, alg jose.SignatureAlgorithm, kid string, key interface{}, claims map[string]interface{}) string {
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: alg, Key: key},
+		(&jose.SignerOptions{}).WithType("JWT").WithHeader(jose.HeaderKey("kid"), kid))
+	require.NoError(t, err)
+
+	token, err := jwt.Signed(signer).Claims(claims).Serialize()
+	require.NoError(t, err)
+	return token
+}
+
+func newJWTRouter(keys *jwtKeys) http.Handler {
+	ipConfig := ratelimiter.Config{Limit: 1, Window: time.Minute, BlockTime: time.Minute}
+	rateLimiter := ratelimiter.NewRateLimiter(storage.NewMockStorage(), ipConfig,
+		ratelimiter.WithTypeConfig(ratelimiter.JWTLimit, ratelimiter.Config{Limit: 2, Window: time.Minute, BlockTime: time.Minute}),
+		ratelimiter.WithTierConfig("pro", ratelimiter.Config{Limit: 5, Window: time.Minute, BlockTime: time.Minute}),
+	)
+
+	mw := middleware.NewRateLimiterMiddleware(rateLimiter,
+		middleware.WithKeyExtractor(middleware.FirstOf(
+			middleware.JWT(middleware.JWTConfig{Keys: keys.jwks, KeyClaim: "client_id", TierClaim: "tier"}),
+			middleware.IP(),
+		)),
+	)
+
+	return mw.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+}
+
+func serveWithBearer(handler http.Handler, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.RemoteAddr = "192.168.1.1:12345"
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestJWT_SignatureAlgorithms(t *testing.T) {
+	keys := newJWTKeys(t)
+	exp := time.Now().Add(time.Hour).Unix()
+
+	tokens := map[string]string{
+		"hmac": signJWT(t, jose.HS256, "hmac", keys.hmac, map[string]interface{}{"client_id": "hmac-client", "exp": exp}),
+		"rsa":  signJWT(t, jose.RS256, "rsa", keys.rsa, map[string]interface{}{"client_id": "rsa-client", "exp": exp}),
+		"ec":   signJWT(t, jose.ES256, "ec", keys.ec, map[string]interface{}{"client_id": "ec-client", "exp": exp}),
+	}
+
+	for name, token := range tokens {
+		handler := newJWTRouter(keys)
+		for i := 0; i < 2; i++ {
+			recorder := serveWithBearer(handler, token)
+			assert.Equal(t, http.StatusOK, recorder.Code, "%s request %d", name, i+1)
+			assert.Equal(t, "jwt", recorder.Header().Get("X-RateLimit-Type"), name)
+			assert.Equal(t, "2", recorder.Header().Get("X-RateLimit-Limit"), name)
+		}
+		assert.Equal(t, http.StatusTooManyRequests, serveWithBearer(handler, token).Code, name)
+	}
+}
+
+func TestJWT_TierFromClaim(t *testing.T) {
+	keys := newJWTKeys(t)
+	handler := newJWTRouter(keys)
+
+	token := signJWT(t, jose.HS256, "hmac", keys.hmac, map[string]interface{}{
+		"client_id": "acme",
+		"tier":      "pro",
+		"exp":       time.Now().Add(time.Hour).Unix(),
+	})
+
+	recorder := serveWithBearer(handler, token)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "5", recorder.Header().Get("X-RateLimit-Limit"))
+}
+
+func TestJWT_ExpiryByClock(t *testing.T) {
+	keys := newJWTKeys(t)
+	fake := clock.NewFake(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC))
+	extractor := middleware.JWT(middleware.JWTConfig{Keys: keys.jwks, KeyClaim: "client_id", Clock: fake})
+
+	token := signJWT(t, jose.HS256, "hmac", keys.hmac, map[string]interface{}{
+		"client_id": "acme", "exp": fake.Now().Add(time.Hour).Unix(),
+	})
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	ids := extractor.Extract(req)
+	require.Len(t, ids, 1)
+	assert.Equal(t, ratelimiter.JWTLimit, ids[0].Type)
+
+	fake.Advance(2 * time.Hour)
+	assert.Empty(t, extractor.Extract(req))
+}
+
+func TestJWT_InvalidTokensFallBackToIP(t *testing.T) {
+	keys := newJWTKeys(t)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	tokens := map[string]string{
+		"missing":   "",
+		"malformed": "not-a-jwt",
+		"expired": signJWT(t, jose.HS256, "hmac", keys.hmac, map[string]interface{}{
+			"client_id": "acme", "exp": time.Now().Add(-time.Hour).Unix(),
+		}),
+		"bad signature": signJWT(t, jose.RS256, "rsa", otherKey, map[string]interface{}{
+			"client_id": "acme", "exp": time.Now().Add(time.Hour).Unix(),
+		}),
+		"missing claim": signJWT(t, jose.HS256, "hmac", keys.hmac, map[string]interface{}{
+			"sub": "acme", "exp": time.Now().Add(time.Hour).Unix(),
+		}),
+	}
+
+	for name, token := range tokens {
+		handler := newJWTRouter(keys)
+		recorder := serveWithBearer(handler, token)
+		assert.Equal(t, http.StatusOK, recorder.Code, name)
+		assert.Equal(t, "ip", recorder.Header().Get("X-RateLimit-Type"), name)
+		assert.Equal(t, "1", recorder.Header().Get("X-RateLimit-Limit"), name)
+	}
+}
@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tiago-kimura/rate-limiter/pkg/middleware"
+	"github.com/tiago-kimura/rate-limiter/pkg/ratelimiter"
+	"github.com/tiago-kimura/rate-limiter/pkg/storage"
+)
+
+func TestRateLimiterMiddleware_ShedsLowPriorityFirst(t *testing.T) {
+	mockStorage := storage.NewMockStorage()
+	rateLimiter := ratelimiter.NewRateLimiter(mockStorage,
+		ratelimiter.Config{Limit: 100, Window: time.Minute, BlockTime: time.Minute},
+		ratelimiter.WithTokenConfig("paid", ratelimiter.Config{Limit: 100, Window: time.Minute, BlockTime: time.Minute, Priority: 1}),
+	)
+	shedder := ratelimiter.NewShedder(mockStorage, ratelimiter.SheddingConfig{
+		Capacity:   4,
+		Window:     time.Minute,
+		Thresholds: map[int]float64{0: 0.5},
+	})
+	handler := middleware.NewRateLimiterMiddleware(rateLimiter, middleware.WithShedder(shedder)).
+		Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+
+	serve := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/test", nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+		if token != "" {
+			req.Header.Set("API_KEY", token)
+		}
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	assert.Equal(t, http.StatusOK, serve("").Code)
+	assert.Equal(t, http.StatusOK, serve("").Code)
+
+	recorder := serve("")
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "load_shed")
+
+	assert.Equal(t, http.StatusOK, serve("paid").Code)
+	assert.Equal(t, http.StatusOK, serve("paid").Code)
+	assert.Equal(t, http.StatusServiceUnavailable, serve("paid").Code)
+}
+
+func TestRateLimiterMiddleware_ShedRequestsDoNotCount(t *testing.T) {
+	mockStorage := storage.NewMockStorage()
+	rateLimiter := ratelimiter.NewRateLimiter(mockStorage,
+		ratelimiter.Config{Limit: 3, Window: time.Minute, BlockTime: time.Minute},
+	)
+	shedder := ratelimiter.NewShedder(mockStorage, ratelimiter.SheddingConfig{
+		Capacity:   2,
+		Window:     time.Minute,
+		Thresholds: map[int]float64{0: 0.5},
+	})
+	handler := middleware.NewRateLimiterMiddleware(rateLimiter, middleware.WithShedder(shedder)).
+		Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+
+	serve := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/test", nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	assert.Equal(t, http.StatusOK, serve().Code)
+
+	// Retrying through an overload must not use up the client's own limit.
+	for i := 0; i < 5; i++ {
+		recorder := serve()
+		assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+		assert.Equal(t, "2", recorder.Header().Get("X-RateLimit-Remaining"))
+	}
+
+	count, err := mockStorage.Get(context.Background(), "ip:192.168.1.1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	blocked, err := mockStorage.Get(context.Background(), "blocked:ip:192.168.1.1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), blocked)
+}